@@ -5,10 +5,12 @@ package main
 
 import (
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/jfitz/virtual-processor/module"
 	"github.com/jfitz/virtual-processor/vputils"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -61,8 +63,121 @@ func evaluateI16(expression string) []byte {
 	return []byte{byteValue1, byteValue2}
 }
 
+// encode a data address as an immediate value of the given width
+func evaluateAddressValue(label string, address vputils.Address, width string) ([]byte, error) {
+	bytes := []byte{}
+
+	switch width {
+	case "BYTE":
+		if address.Value > 0xff {
+			return bytes, errors.New("Address of '" + label + "' does not fit in BYTE")
+		}
+		bytes = []byte{byte(address.Value)}
+	case "I16":
+		if address.Value > 0xffff {
+			return bytes, errors.New("Address of '" + label + "' does not fit in I16")
+		}
+		bytes = evaluateI16(strconv.Itoa(address.Value))
+	default:
+		return bytes, errors.New("Cannot use address of '" + label + "' as " + width)
+	}
+
+	return bytes, nil
+}
+
 type labelTable map[string]vputils.Address
 
+// the code labels declared EXTERNAL, left for the linker
+type externalTable map[string]bool
+
+// collect the EXTERNAL declarations
+// only uppercase labels are exported, so only they can be external
+func collectExternals(tokenGroups []tokenGroup) externalTable {
+	externals := make(externalTable)
+
+	for _, tokens := range tokenGroups {
+		name := tokens.Targets[0]
+		if !vputils.IsUpper(name[0]) {
+			vputils.CheckAndExit(errors.New("External symbol '" + name + "' must begin with an uppercase letter"))
+		}
+
+		externals[name] = true
+	}
+
+	return externals
+}
+
+// an external symbol cannot also be defined in the module
+func checkExternals(declared externalTable, codeLabels labelTable) {
+	names := []string{}
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := codeLabels[name]; ok {
+			vputils.CheckAndExit(errors.New("External symbol '" + name + "' is also defined"))
+		}
+	}
+}
+
+type importTable map[string]int
+
+// an imported symbol is named by library and symbol, as in stdlib.PRINT_NUM
+func isImportTarget(token string) bool {
+	parts := strings.Split(token, ".")
+
+	return len(parts) == 2 && isTarget(parts[0]) && isTarget(parts[1])
+}
+
+// collect imported symbols in order of first use
+func collectImports(tokenGroups []tokenGroup) ([]vputils.NameValue, importTable) {
+	imports := []vputils.NameValue{}
+	indexes := make(importTable)
+
+	for _, tokens := range tokenGroups {
+		for _, target := range tokens.Targets {
+			if _, ok := indexes[target]; !ok && isImportTarget(target) {
+				parts := strings.Split(target, ".")
+				indexes[target] = len(imports)
+				imports = append(imports, vputils.NameValue{parts[0], parts[1]})
+			}
+		}
+	}
+
+	if len(imports) > 256 {
+		vputils.CheckAndExit(errors.New("Too many imported symbols"))
+	}
+
+	return imports, indexes
+}
+
+// a far call to an imported symbol carries the index of the import
+func buildFarCallInstruction(opcode string, target string, imports importTable) ([]byte, error) {
+	if opcode != "CALL" {
+		return nil, errors.New("Imported symbol '" + target + "' can only be called")
+	}
+
+	farCall, _ := module.FindOpcode(opcode, "IMPORT")
+	index := imports[target]
+	instruction := []byte{farCall, byte(index)}
+
+	return instruction, nil
+}
+
+// JUMP and CALL take code targets, everything else takes data targets
+func isCodeTarget(opcode string) bool {
+	return opcode == "JUMP" || opcode == "CALL"
+}
+
+// a relocation names the segment and width of an address field
+func makeRelocation(offset int, segment string, width int) vputils.NameValue {
+	relocation := module.MakeRelocation(offset, segment, width)
+
+	return relocation.ToNameValue()
+}
+
 func buildInstructionByAddressMode(opcodemap module.TargetWidthToOpcodes, width string, value string, dataTarget string, target string, dataLabels labelTable, codeLabels labelTable, resolveAddress bool) ([]byte, error) {
 	opcodes, ok := opcodemap[width]
 
@@ -70,6 +185,19 @@ func buildInstructionByAddressMode(opcodemap module.TargetWidthToOpcodes, width
 		return nil, errors.New("Set '" + width + "' not found")
 	}
 
+	instruction, err := buildAddressModeInstruction(opcodes, width, value, dataTarget, target, dataLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	if instruction[0] == module.UnusedOpcode {
+		return nil, errors.New("Address mode not supported for '" + width + "'")
+	}
+
+	return instruction, nil
+}
+
+func buildAddressModeInstruction(opcodes []byte, width string, value string, dataTarget string, target string, dataLabels labelTable) ([]byte, error) {
 	if len(value) == 0 && len(dataTarget) == 0 && len(target) == 0 {
 		// stack
 		opcode := opcodes[3]
@@ -112,7 +240,8 @@ func buildInstructionByAddressMode(opcodemap module.TargetWidthToOpcodes, width
 			err := errors.New("Undefined data label '" + target + "'")
 			vputils.CheckAndExit(err)
 		}
-		bytes := address.ToBytes()
+		bytes, err := evaluateAddressValue(target, address, width)
+		vputils.CheckAndExit(err)
 		instruction := append(opcode, bytes...)
 		return instruction, nil
 	}
@@ -146,10 +275,10 @@ func buildInstructionByAddressMode(opcodemap module.TargetWidthToOpcodes, width
 	return nil, errors.New("Invalid opcode")
 }
 
-func buildJumpCallInstruction(opcode byte, target string, dataLabels labelTable, codeLabels labelTable, resolveAddress bool) ([]byte, error) {
-	// TODO avoid hard-coded values
+func buildJumpCallInstruction(opcode byte, target string, dataLabels labelTable, codeLabels labelTable, resolveAddress bool, codeAddressWidth int, dataAddressWidth int) ([]byte, error) {
 	instruction := []byte{opcode}
-	isJump := opcode == 0xD0 || opcode == 0xD1
+	spec, _ := module.FindInstruction(opcode)
+	isJump := spec.Operand == "CODE"
 
 	if isJump {
 		// for jump and call instructions, append the target address from code labels
@@ -157,16 +286,12 @@ func buildJumpCallInstruction(opcode byte, target string, dataLabels labelTable,
 		err := errors.New("")
 
 		if !ok {
-			if resolveAddress {
-				err = errors.New("Undefined code label '" + target + "'")
-			} else {
-				address, err = vputils.MakeAddress(0, 1, 0)
-			}
-
+			// placeholder, resolved in pass 2 or by the linker
+			// pass 2 rejects targets that are neither defined nor external
+			address, err = vputils.MakeAddress(0, codeAddressWidth, 0)
 			vputils.CheckAndExit(err)
 		}
 
-		// TODO: check address is within code address width
 		bytes := address.ToBytes()
 		instruction := append(instruction, bytes...)
 		return instruction, nil
@@ -180,31 +305,37 @@ func buildJumpCallInstruction(opcode byte, target string, dataLabels labelTable,
 		if resolveAddress {
 			err = errors.New("Undefined data label '" + target + "'")
 		} else {
-			address, err = vputils.MakeAddress(0, 1, 0)
+			address, err = vputils.MakeAddress(0, dataAddressWidth, 0)
 		}
 
 		vputils.CheckAndExit(err)
 	}
 
-	// TODO: check address is within data address width
 	bytes := address.ToBytes()
 	instruction = append(instruction, bytes...)
 	return instruction, nil
 }
 
-func decodeOpcode(text string, instructionAddress vputils.Address, width string, value string, dataTarget string, target string, opcodeDefs map[string]module.OpcodeBytes, resolveAddress bool, codeLabels labelTable, dataLabels labelTable) ([]byte, error) {
+func decodeOpcode(text string, instructionAddress vputils.Address, width string, value string, dataTarget string, target string, opcodeDefs map[string]module.OpcodeBytes, resolveAddress bool, codeLabels labelTable, dataLabels labelTable, imports importTable, codeAddressWidth int, dataAddressWidth int) ([]byte, error) {
 	opcodeDef, ok := opcodeDefs[text]
 
 	if !ok {
 		return []byte{}, errors.New("Invalid opcode: '" + text + "' ")
 	}
 
+	if isImportTarget(target) {
+		return buildFarCallInstruction(text, target, imports)
+	}
+
 	// assume we have a simple opcode (with no target)
 	instruction := []byte{opcodeDef.Opcode}
 	addressOpcodes := opcodeDef.AddressOpcodes
 
+	// a mnemonic with a plain form, such as EXIT, uses it when there is no width
+	plain := len(width) == 0 && opcodeDef.Opcode != module.UnusedOpcode
+
 	var err error
-	if len(addressOpcodes) > 0 {
+	if len(addressOpcodes) > 0 && !plain {
 		// select instruction depends on target
 		instruction, err = buildInstructionByAddressMode(addressOpcodes, width, value, dataTarget, target, dataLabels, codeLabels, resolveAddress)
 		vputils.CheckAndExit(err)
@@ -212,15 +343,15 @@ func decodeOpcode(text string, instructionAddress vputils.Address, width string,
 
 	if len(addressOpcodes) == 0 && len(target) > 0 {
 		opcode := instruction[0]
-		instruction, err = buildJumpCallInstruction(opcode, target, dataLabels, codeLabels, resolveAddress)
+		instruction, err = buildJumpCallInstruction(opcode, target, dataLabels, codeLabels, resolveAddress, codeAddressWidth, dataAddressWidth)
 		vputils.CheckAndExit(err)
 	}
 
 	return instruction, nil
 }
 
-func getInstruction(text string, instructionAddress vputils.Address, width string, value string, dataTarget string, target string, opcodeDefs map[string]module.OpcodeBytes, resolveAddress bool, dataLabels labelTable, codeLabels labelTable) []byte {
-	instruction, err := decodeOpcode(text, instructionAddress, width, value, dataTarget, target, opcodeDefs, resolveAddress, codeLabels, dataLabels)
+func getInstruction(text string, instructionAddress vputils.Address, width string, value string, dataTarget string, target string, opcodeDefs map[string]module.OpcodeBytes, resolveAddress bool, dataLabels labelTable, codeLabels labelTable, imports importTable, codeAddressWidth int, dataAddressWidth int) []byte {
+	instruction, err := decodeOpcode(text, instructionAddress, width, value, dataTarget, target, opcodeDefs, resolveAddress, codeLabels, dataLabels, imports, codeAddressWidth, dataAddressWidth)
 	vputils.CheckAndExit(err)
 
 	if len(instruction) == 0 {
@@ -231,7 +362,7 @@ func getInstruction(text string, instructionAddress vputils.Address, width strin
 	return instruction
 }
 
-func checkDataLabel(label string, labels labelTable) {
+func checkDataLabel(label string, labels map[string]int) {
 	if label == "" {
 		vputils.CheckAndExit(errors.New("Data declaration requires label"))
 	}
@@ -279,66 +410,164 @@ func encodeConditional(conditional string, not string) []byte {
 	return prefix
 }
 
-func generateData(tokenGroups []tokenGroup) (vputils.Vector, labelTable) {
-	tabs := "\t\t\t"
-	fmt.Println(tabs + "DATA")
+// evaluate the bytes for a data declaration
+func evaluateDeclaration(tokens tokenGroup) []byte {
+	width := tokens.Widths[0]
+	value := tokens.Values[0]
+
+	values := []byte{}
+	switch width {
+	case "BYTE":
+		// evaluate numeric or text (data label) but nothing else
+		value1 := evaluateByte(value)
+		values = append(values, value1...)
+	case "STRING":
+		// target must be a string
+		chars := dequoteString(value)
+		values = append(values, chars...)
+	default:
+		vputils.CheckAndExit(errors.New("Invalid data specification"))
+	}
+
+	return values
+}
 
-	data := vputils.Vector{}
-	dataLabels := make(labelTable)
+func isConstDeclaration(tokens tokenGroup) bool {
+	return contains(tokens.Segments, "CONST")
+}
+
+func isReserveDeclaration(tokens tokenGroup) bool {
+	return tokens.Widths[0] == "RESERVE"
+}
+
+// evaluate the size of a reserved (uninitialized) area
+func evaluateReserve(tokens tokenGroup) int {
+	value := tokens.Values[0]
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 {
+		vputils.CheckAndExit(errors.New("Invalid RESERVE size '" + value + "'"))
+	}
+
+	return size
+}
+
+// print a segment of declarations, with offset, directive, and contents
+func printDataSegment(name string, tokenGroups []tokenGroup, dataLabels labelTable, addressFormat string) {
+	tabs := "\t\t\t"
+	fmt.Println(tabs + name)
 
 	for _, tokens := range tokenGroups {
 		label := tokens.Labels[0]
-		checkDataLabel(label, dataLabels)
-
-		// add the label to our table
-		addressValue := len(data)
-		if addressValue > 255 {
-			vputils.CheckAndExit(errors.New("Exceeded data label table size"))
-		}
-		address, err := vputils.MakeAddress(addressValue, 1, len(data))
-		vputils.CheckAndExit(err)
-		dataLabels[label] = address
+		width := tokens.Widths[0]
+		location := dataLabels[label].Value
 
 		// write the label on a line by itself
 		if len(label) > 0 {
 			fmt.Printf("%s:\n", label)
 		}
 
-		width := tokens.Widths[0]
-		value := tokens.Values[0]
-
-		values := []byte{}
-		switch width {
-		case "BYTE":
-			// evaluate numeric or text (data label) but nothing else
-			value1 := evaluateByte(value)
-			values = append(values, value1...)
-		case "STRING":
-			// target must be a string
-			chars := dequoteString(value)
-			values = append(values, chars...)
-		default:
-			vputils.CheckAndExit(errors.New("Invalid data specification"))
+		if isReserveDeclaration(tokens) {
+			// reserved space has a size but no contents
+			size := evaluateReserve(tokens)
+			fmt.Printf(addressFormat+"%s%s\t\t%d\n", location, tabs, width, size)
+			continue
 		}
 
-		// print offset, directive, and contents
-		location := len(data)
+		values := evaluateDeclaration(tokens)
 
 		if len(values) == 0 {
-			fmt.Printf("%02X%s%s\n", location, tabs, width)
+			fmt.Printf(addressFormat+"%s%s\n", location, tabs, width)
 		} else {
-			fmt.Printf("%02X%s%s\t\t% X\n", location, tabs, width, values)
-			data = append(data, values...)
+			fmt.Printf(addressFormat+"%s%s\t\t% X\n", location, tabs, width, values)
 		}
 	}
 
 	fmt.Println(tabs + "ENDSEGMENT")
 	fmt.Println()
+}
+
+// data, reserved space, and constants share one address space, in that order
+func generateData(tokenGroups []tokenGroup, minimumAddressWidth int) (vputils.Vector, int, vputils.Vector, labelTable, int) {
+	dataGroups := []tokenGroup{}
+	bssGroups := []tokenGroup{}
+	constGroups := []tokenGroup{}
+
+	for _, tokens := range tokenGroups {
+		isConst := isConstDeclaration(tokens)
+		isReserve := isReserveDeclaration(tokens)
+
+		if isConst && isReserve {
+			vputils.CheckAndExit(errors.New("Cannot RESERVE space in CONST segment"))
+		}
+
+		if isConst {
+			constGroups = append(constGroups, tokens)
+		}
+
+		if isReserve {
+			bssGroups = append(bssGroups, tokens)
+		}
+
+		if !isConst && !isReserve {
+			dataGroups = append(dataGroups, tokens)
+		}
+	}
 
-	return data, dataLabels
+	data := vputils.Vector{}
+	bssSize := 0
+	consts := vputils.Vector{}
+	locations := make(map[string]int)
+
+	// lay out the data, reserved space, and constants to learn their size
+	for _, tokens := range dataGroups {
+		label := tokens.Labels[0]
+		checkDataLabel(label, locations)
+		locations[label] = len(data)
+
+		data = append(data, evaluateDeclaration(tokens)...)
+	}
+
+	for _, tokens := range bssGroups {
+		label := tokens.Labels[0]
+		checkDataLabel(label, locations)
+		locations[label] = len(data) + bssSize
+
+		bssSize += evaluateReserve(tokens)
+	}
+
+	for _, tokens := range constGroups {
+		label := tokens.Labels[0]
+		checkDataLabel(label, locations)
+		locations[label] = len(data) + bssSize + len(consts)
+
+		consts = append(consts, evaluateDeclaration(tokens)...)
+	}
+
+	// address width depends on the size of the whole data space
+	dataSize := len(data) + bssSize + len(consts)
+	dataAddressWidth := vputils.AddressWidth(dataSize)
+	if dataAddressWidth < minimumAddressWidth {
+		dataAddressWidth = minimumAddressWidth
+	}
+	addressFormat := vputils.AddressFormat(dataAddressWidth)
+
+	// add the labels to our table
+	dataLabels := make(labelTable)
+	for label, location := range locations {
+		address, err := vputils.MakeAddress(location, dataAddressWidth, dataSize)
+		vputils.CheckAndExit(err)
+		dataLabels[label] = address
+	}
+
+	printDataSegment("DATA", dataGroups, dataLabels, addressFormat)
+	printDataSegment("BSS", bssGroups, dataLabels, addressFormat)
+	printDataSegment("CONST", constGroups, dataLabels, addressFormat)
+
+	return data, bssSize, consts, dataLabels, dataAddressWidth
 }
 
-func generateCode1(tokenGroups []tokenGroup, opcodeDefs map[string]module.OpcodeBytes, dataLabels labelTable) labelTable {
+func generateCode1(tokenGroups []tokenGroup, opcodeDefs map[string]module.OpcodeBytes, dataLabels labelTable, imports importTable, codeAddressWidth int, dataAddressWidth int) (labelTable, int) {
 	codeLabels := make(labelTable)
 	code := vputils.Vector{}
 
@@ -384,12 +613,8 @@ func generateCode1(tokenGroups []tokenGroup, opcodeDefs map[string]module.Opcode
 		}
 
 		address := len(code)
-		// TODO: limit is based on address width
-		if address > 255 {
-			vputils.CheckAndExit(errors.New("Exceeded code label table size"))
-		}
 
-		instructionAddress, err := vputils.MakeAddress(address, 1, len(code))
+		instructionAddress, err := vputils.MakeAddress(address, codeAddressWidth, len(code))
 		vputils.CheckAndExit(err)
 
 		if len(label) > 0 {
@@ -399,21 +624,25 @@ func generateCode1(tokenGroups []tokenGroup, opcodeDefs map[string]module.Opcode
 		}
 
 		// decode the instruction
-		instruction := getInstruction(opcode, instructionAddress, width, value, dataTarget, target, opcodeDefs, false, dataLabels, codeLabels)
+		instruction := getInstruction(opcode, instructionAddress, width, value, dataTarget, target, opcodeDefs, false, dataLabels, codeLabels, imports, codeAddressWidth, dataAddressWidth)
 
 		// inject code here, to keep length of code as the address of the start of the conditional
 		code = append(code, prefix...)
 		code = append(code, instruction...)
 	}
 
-	return codeLabels
+	return codeLabels, len(code)
 }
 
-func generateCode2(tokenGroups []tokenGroup, opcodeDefs map[string]module.OpcodeBytes, dataLabels labelTable, codeLabels labelTable) vputils.Vector {
+func generateCode2(tokenGroups []tokenGroup, opcodeDefs map[string]module.OpcodeBytes, dataLabels labelTable, codeLabels labelTable, imports importTable, declared externalTable, codeAddressWidth int, dataAddressWidth int) (vputils.Vector, []vputils.NameValue, []vputils.NameValue) {
 	tabs := "\t\t\t"
 	fmt.Println(tabs + "CODE")
 
+	addressFormat := vputils.AddressFormat(codeAddressWidth)
+
 	code := vputils.Vector{}
+	relocations := []vputils.NameValue{}
+	externals := []vputils.NameValue{}
 
 	for _, tokens := range tokenGroups {
 		// write the directive or instruction
@@ -457,12 +686,8 @@ func generateCode2(tokenGroups []tokenGroup, opcodeDefs map[string]module.Opcode
 		}
 
 		address := len(code)
-		// TODO: limit is based on address width
-		if address > 255 {
-			vputils.CheckAndExit(errors.New("Exceeded code label table size"))
-		}
 
-		instructionAddress, err := vputils.MakeAddress(address, 1, len(code))
+		instructionAddress, err := vputils.MakeAddress(address, codeAddressWidth, len(code))
 		vputils.CheckAndExit(err)
 
 		if len(label) > 0 {
@@ -470,8 +695,15 @@ func generateCode2(tokenGroups []tokenGroup, opcodeDefs map[string]module.Opcode
 			fmt.Printf("%s:\n", label)
 		}
 
+		// a code target must be defined here or declared external
+		if len(target) > 0 && isCodeTarget(opcode) && !isImportTarget(target) {
+			if _, ok := codeLabels[target]; !ok && !declared[target] {
+				vputils.CheckAndExit(errors.New("Undefined code label '" + target + "'"))
+			}
+		}
+
 		// decode the instruction
-		instruction := getInstruction(opcode, instructionAddress, width, value, dataTarget, target, opcodeDefs, true, dataLabels, codeLabels)
+		instruction := getInstruction(opcode, instructionAddress, width, value, dataTarget, target, opcodeDefs, true, dataLabels, codeLabels, imports, codeAddressWidth, dataAddressWidth)
 
 		hexBytes := append(prefix, instruction...)
 		location := len(code)
@@ -494,7 +726,29 @@ func generateCode2(tokenGroups []tokenGroup, opcodeDefs map[string]module.Opcode
 		if len(width) > 0 {
 			fullOpcode += " " + width
 		}
-		fmt.Printf("%02X\t%s%s%s\t%s%s%s\n", location, instructionString, wordTabs, fullOpcode, target, dataTarget, value)
+		fmt.Printf(addressFormat+"\t%s%s%s\t%s%s%s\n", location, instructionString, wordTabs, fullOpcode, target, dataTarget, value)
+
+		// record the address field, which follows the opcode
+		fieldOffset := len(code) + len(prefix) + 1
+		fieldWidth := len(instruction) - 1
+
+		if isImportTarget(target) {
+			relocation := makeRelocation(fieldOffset, "IMPORT", fieldWidth)
+			relocations = append(relocations, relocation)
+		} else if len(target) > 0 && isCodeTarget(opcode) {
+			if _, ok := codeLabels[target]; ok {
+				relocation := makeRelocation(fieldOffset, "CODE", fieldWidth)
+				relocations = append(relocations, relocation)
+			} else {
+				external := vputils.NameValue{target, strconv.Itoa(fieldOffset)}
+				externals = append(externals, external)
+			}
+		}
+
+		if (len(target) > 0 && !isCodeTarget(opcode) && !isImportTarget(target)) || len(dataTarget) > 0 {
+			relocation := makeRelocation(fieldOffset, "DATA", fieldWidth)
+			relocations = append(relocations, relocation)
+		}
 
 		code = append(code, prefix...)
 		code = append(code, instruction...)
@@ -503,7 +757,7 @@ func generateCode2(tokenGroups []tokenGroup, opcodeDefs map[string]module.Opcode
 	fmt.Println(tabs + "ENDSEGMENT")
 	fmt.Println()
 
-	return code
+	return code, relocations, externals
 }
 
 func makeModuleProperties() []vputils.NameValue {
@@ -543,9 +797,16 @@ func makeDataProperties(dataAddressWidth int) []vputils.NameValue {
 func makeExports(codeLabels labelTable) []vputils.NameValue {
 	exports := []vputils.NameValue{}
 
-	for label, address := range codeLabels {
+	// sort the labels so the module contents do not depend on map order
+	labels := []string{}
+	for label := range codeLabels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	for _, label := range labels {
 		if vputils.IsUpper(label[0]) {
-			i := address.Value
+			i := codeLabels[label].Value
 			s := strconv.Itoa(i)
 			nv := vputils.NameValue{label, s}
 			exports = append(exports, nv)
@@ -659,6 +920,8 @@ func tokenizeSource(source []string) []lineAndTokens {
 
 type tokenGroup struct {
 	Labels       []string
+	Externs      []string
+	Segments     []string
 	Nots         []string
 	Conditionals []string
 	Opcodes      []string
@@ -798,9 +1061,11 @@ func groupTokens(tokens tokenList) tokenGroup {
 	groups := tokenGroup{}
 
 	notList := []string{"NOT"}
+	externList := []string{"EXTERNAL"}
+	segmentList := []string{"CONST"}
 	conditionalList := []string{"ZERO", "POSITIVE", "NEGATIVE"}
-	widthList := []string{"BYTE", "I16", "I32", "I64", "F32", "F64", "STRING"}
-	opcodeList := []string{"ADD", "SUB", "MUL", "DIV", "CMP", "PUSH", "POP", "EXIT", "KCALL", "OUT", "NOP", "JUMP", "CALL", "RET", "AND", "OR", "FLAGS", "INC", "DEC"}
+	widthList := []string{"BYTE", "I16", "I32", "I64", "F32", "F64", "STRING", "RESERVE"}
+	opcodeList := module.Mnemonics()
 
 	for _, token := range tokens {
 		handled := false
@@ -820,6 +1085,16 @@ func groupTokens(tokens tokenList) tokenGroup {
 			handled = true
 		}
 
+		if contains(externList, token) {
+			groups.Externs = append(groups.Externs, token)
+			handled = true
+		}
+
+		if contains(segmentList, token) {
+			groups.Segments = append(groups.Segments, token)
+			handled = true
+		}
+
 		if contains(widthList, token) {
 			groups.Widths = append(groups.Widths, token)
 			handled = true
@@ -856,6 +1131,11 @@ func groupTokens(tokens tokenList) tokenGroup {
 			handled = true
 		}
 
+		if !handled && isImportTarget(token) {
+			groups.Targets = append(groups.Targets, token)
+			handled = true
+		}
+
 		if !handled {
 			groups.Others = append(groups.Others, token)
 		}
@@ -879,6 +1159,8 @@ func group(list []lineAndTokens) []lineAndTokenGroup {
 func validateLine(lineAndTokens lineAndTokenGroup) string {
 	tokens := lineAndTokens.Tokens
 	countLabels := len(tokens.Labels)
+	countExterns := len(tokens.Externs)
+	countSegments := len(tokens.Segments)
 	countNots := len(tokens.Nots)
 	countConditionals := len(tokens.Conditionals)
 	countOpcodes := len(tokens.Opcodes)
@@ -895,14 +1177,22 @@ func validateLine(lineAndTokens lineAndTokenGroup) string {
 	}
 
 	// a blank line is valid
-	if countLabels == 0 && countNots == 0 && countConditionals == 0 &&
+	if countLabels == 0 && countExterns == 0 && countSegments == 0 && countNots == 0 && countConditionals == 0 &&
 		countOpcodes == 0 && countWidths == 0 && countTargets == 0 &&
 		countDataTargets == 0 && countValues == 0 {
 		return ""
 	}
 
+	// an external declaration names one code label
+	if countLabels == 0 && countExterns == 1 && countSegments == 0 && countNots == 0 && countConditionals == 0 &&
+		countOpcodes == 0 && countWidths == 0 && countTargets == 1 &&
+		countDataTargets == 0 && countValues == 0 {
+		return ""
+	}
+
 	// a data declaration has a label, width, and value
-	if countLabels == 1 && countNots == 0 && countConditionals == 0 &&
+	// and may have a segment
+	if countLabels == 1 && countExterns == 0 && countSegments < 2 && countNots == 0 && countConditionals == 0 &&
 		countOpcodes == 0 && countWidths == 1 && countTargets == 0 &&
 		countDataTargets == 0 && countValues == 1 {
 		return ""
@@ -912,7 +1202,7 @@ func validateLine(lineAndTokens lineAndTokenGroup) string {
 
 	// opcodes may have a label, may have a width, may have a value or target
 	// may have a conditional and may have a NOT
-	if countLabels < 2 && countNots < 2 && countConditionals < 2 &&
+	if countLabels < 2 && countExterns == 0 && countSegments == 0 && countNots < 2 && countConditionals < 2 &&
 		countOpcodes == 1 && countWidths < 2 && countAllTargets < 2 {
 		return ""
 	}
@@ -920,7 +1210,8 @@ func validateLine(lineAndTokens lineAndTokenGroup) string {
 	return "Wrong combination of symbols"
 }
 
-func validate(groupList []lineAndTokenGroup) ([]tokenGroup, []tokenGroup, []string) {
+func validate(groupList []lineAndTokenGroup) ([]tokenGroup, []tokenGroup, []tokenGroup, []string) {
+	externTokens := make([]tokenGroup, 0)
 	dataTokens := make([]tokenGroup, 0)
 	codeTokens := make([]tokenGroup, 0)
 	invalids := make([]string, 0)
@@ -929,7 +1220,10 @@ func validate(groupList []lineAndTokenGroup) ([]tokenGroup, []tokenGroup, []stri
 		message := validateLine(lineAndTokens)
 		if len(message) == 0 {
 			tokens := lineAndTokens.Tokens
-			if len(tokens.Opcodes) == 1 {
+			if len(tokens.Externs) == 1 {
+				// external declaration
+				externTokens = append(externTokens, tokens)
+			} else if len(tokens.Opcodes) == 1 {
 				// instruction line
 				codeTokens = append(codeTokens, tokens)
 			} else {
@@ -943,11 +1237,26 @@ func validate(groupList []lineAndTokenGroup) ([]tokenGroup, []tokenGroup, []stri
 		}
 	}
 
-	return dataTokens, codeTokens, invalids
+	return externTokens, dataTokens, codeTokens, invalids
 }
 
 func main() {
-	args := os.Args[1:]
+	codeWidthPtr := flag.Int("code-width", 1, "Minimum code address width.")
+	dataWidthPtr := flag.Int("data-width", 1, "Minimum data address width.")
+
+	flag.Parse()
+
+	minimumCodeAddressWidth := *codeWidthPtr
+	minimumDataAddressWidth := *dataWidthPtr
+
+	for _, width := range []int{minimumCodeAddressWidth, minimumDataAddressWidth} {
+		if width != 1 && width != 2 && width != 4 {
+			fmt.Println("Address width must be 1, 2, or 4")
+			os.Exit(1)
+		}
+	}
+
+	args := flag.Args()
 
 	if len(args) == 0 {
 		fmt.Println("No source file specified")
@@ -967,16 +1276,13 @@ func main() {
 
 	// create opcode definitions
 	opcodeDefs := module.MakeMnemonicTargetWidthAddressModes()
-	instructionSetVersion := "1"
-
-	codeAddressWidth := 1
-	dataAddressWidth := 1
+	instructionSetVersion := module.InstructionSetVersion
 
 	moduleProperties := makeModuleProperties()
 
 	linesAndTokens := tokenizeSource(source)
 	groupsList := group(linesAndTokens)
-	dataTokens, codeTokens, invalids := validate(groupsList)
+	externTokens, dataTokens, codeTokens, invalids := validate(groupsList)
 
 	if len(invalids) > 0 {
 		fmt.Println("Errors found:")
@@ -986,23 +1292,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	data, dataLabels := generateData(dataTokens)
+	data, bssSize, consts, dataLabels, dataAddressWidth := generateData(dataTokens, minimumDataAddressWidth)
 	dataProperties := makeDataProperties(dataAddressWidth)
-	dataPage := module.Page{dataProperties, data, dataAddressWidth}
+	dataPage := module.Page{dataProperties, data, dataAddressWidth, 0}
+	constProperties := makeDataProperties(dataAddressWidth)
+	constPage := module.Page{constProperties, consts, dataAddressWidth, len(consts)}
+
+	imports, importIndexes := collectImports(codeTokens)
+	declared := collectExternals(externTokens)
+
+	// widen code addresses until the code fits
+	codeAddressWidth := minimumCodeAddressWidth
+	codeLabels, codeSize := generateCode1(codeTokens, opcodeDefs, dataLabels, importIndexes, codeAddressWidth, dataAddressWidth)
+	for vputils.AddressWidth(codeSize) > codeAddressWidth {
+		codeAddressWidth = vputils.AddressWidth(codeSize)
+		codeLabels, codeSize = generateCode1(codeTokens, opcodeDefs, dataLabels, importIndexes, codeAddressWidth, dataAddressWidth)
+	}
 
-	codeLabels := generateCode1(codeTokens, opcodeDefs, dataLabels)
+	checkExternals(declared, codeLabels)
 
 	exports := makeExports(codeLabels)
 
-	code := generateCode2(codeTokens, opcodeDefs, dataLabels, codeLabels)
+	code, relocations, externals := generateCode2(codeTokens, opcodeDefs, dataLabels, codeLabels, importIndexes, declared, codeAddressWidth, dataAddressWidth)
 	codeProperties := makeCodeProperties(instructionSetVersion, codeAddressWidth, dataAddressWidth)
-	codePage := module.Page{codeProperties, code, codeAddressWidth}
+	codePage := module.Page{codeProperties, code, codeAddressWidth, 0}
 
 	mod := module.Module{
 		Properties:       moduleProperties,
 		CodePage:         codePage,
 		Exports:          exports,
+		Externals:        externals,
+		Imports:          imports,
+		Relocations:      relocations,
 		DataPage:         dataPage,
+		BSSSize:          bssSize,
+		ConstPage:        constPage,
 		CodeAddressWidth: codeAddressWidth,
 		DataAddressWidth: dataAddressWidth,
 	}
@@ -0,0 +1,377 @@
+/*
+Package main of disassembler
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jfitz/virtual-processor/module"
+	"github.com/jfitz/virtual-processor/vputils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// one line of data declaration
+type declaration struct {
+	Label     string
+	Segment   string
+	Directive string
+	Value     string
+}
+
+func (decl declaration) toString() string {
+	directive := decl.Directive
+	if len(decl.Segment) > 0 {
+		directive = decl.Segment + " " + directive
+	}
+
+	return decl.Label + ":\t" + directive + "\t" + decl.Value
+}
+
+// the address fields of the code, by offset
+type fieldTable map[int]string
+
+func makeLabel(prefix string, address int, width int) string {
+	return fmt.Sprintf(prefix+vputils.AddressFormat(width), address)
+}
+
+// the offset of the operand of an instruction
+func operandOffset(instruction module.DecodedInstruction) int {
+	return instruction.Address + len(instruction.Conditionals) + 1
+}
+
+// can the bytes be written as a string declaration
+func isStringByte(b byte) bool {
+	return b >= 0x20 && b < 0x7f && b != '"'
+}
+
+// relocation segments and external symbols, by field offset
+func makeFieldTables(mod module.Module) (fieldTable, fieldTable, error) {
+	relocations := make(fieldTable)
+	for _, nameValue := range mod.Relocations {
+		relocation, err := module.ParseRelocation(nameValue)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		relocations[relocation.Offset] = relocation.Segment
+	}
+
+	externals := make(fieldTable)
+	for _, nameValue := range mod.Externals {
+		offset, err := strconv.Atoi(nameValue.Value)
+		if err != nil {
+			return nil, nil, errors.New("Invalid reference to " + nameValue.Name)
+		}
+
+		externals[offset] = nameValue.Name
+	}
+
+	return relocations, externals, nil
+}
+
+// label the code addresses, using exports and synthesizing the rest
+func makeCodeLabels(mod module.Module, instructions []module.DecodedInstruction, externals fieldTable) (map[int]string, error) {
+	starts := make(map[int]bool)
+	for _, instruction := range instructions {
+		starts[instruction.Address] = true
+	}
+
+	labels := make(map[int]string)
+	for _, export := range mod.Exports {
+		address, err := strconv.Atoi(export.Value)
+		if err != nil {
+			return nil, errors.New("Invalid address for symbol " + export.Name)
+		}
+
+		if previous, ok := labels[address]; ok {
+			return nil, errors.New("Symbols " + previous + " and " + export.Name + " share an address")
+		}
+
+		labels[address] = export.Name
+	}
+
+	for _, instruction := range instructions {
+		if !instruction.IsJump() {
+			continue
+		}
+
+		if _, ok := externals[operandOffset(instruction)]; ok {
+			continue
+		}
+
+		target := instruction.OperandValue()
+		if !starts[target] {
+			return nil, fmt.Errorf("Jump at %02X to %02X is not to an instruction", instruction.Address, target)
+		}
+
+		if _, ok := labels[target]; !ok {
+			labels[target] = makeLabel("code_", target, mod.CodeAddressWidth)
+		}
+	}
+
+	for address := range labels {
+		if !starts[address] {
+			return nil, fmt.Errorf("Symbol %s at %02X is not at an instruction", labels[address], address)
+		}
+	}
+
+	return labels, nil
+}
+
+// the data addresses referenced by the code
+func makeDataReferences(instructions []module.DecodedInstruction, relocations fieldTable) map[int]bool {
+	references := make(map[int]bool)
+
+	for _, instruction := range instructions {
+		mode := instruction.Definition.AddressMode
+		relocated := relocations[operandOffset(instruction)] == "DATA"
+
+		if mode == "D" || mode == "I" || (mode == "V" && relocated) {
+			references[instruction.OperandValue()] = true
+		}
+	}
+
+	return references
+}
+
+// declare initialized bytes, starting a declaration at each referenced address
+func declareBytes(bytes []byte, start int, references map[int]bool, segment string, dataAddressWidth int) []declaration {
+	declarations := []declaration{}
+
+	i := 0
+	for i < len(bytes) {
+		// a declaration cannot run past the next referenced address
+		end := i + 1
+		for end < len(bytes) && !references[start+end] {
+			end++
+		}
+
+		label := makeLabel("data_", start+i, dataAddressWidth)
+
+		j := i
+		for j < end && isStringByte(bytes[j]) {
+			j++
+		}
+
+		if j > i && j < end && bytes[j] == 0 {
+			value := "\"" + string(bytes[i:j]) + "\""
+			declarations = append(declarations, declaration{label, segment, "STRING", value})
+			i = j + 1
+		} else {
+			value := strconv.Itoa(int(bytes[i]))
+			declarations = append(declarations, declaration{label, segment, "BYTE", value})
+			i++
+		}
+	}
+
+	return declarations
+}
+
+// declare reserved space, starting a declaration at each referenced address
+func declareReserve(size int, start int, references map[int]bool, dataAddressWidth int) []declaration {
+	declarations := []declaration{}
+
+	i := 0
+	for i < size {
+		end := i + 1
+		for end < size && !references[start+end] {
+			end++
+		}
+
+		label := makeLabel("data_", start+i, dataAddressWidth)
+		value := strconv.Itoa(end - i)
+		declarations = append(declarations, declaration{label, "", "RESERVE", value})
+
+		i = end
+	}
+
+	return declarations
+}
+
+// write the conditionals in the form the assembler accepts
+func conditionalsSource(conditionals module.Conditionals) (string, error) {
+	switch conditionals.ToString() {
+	case "":
+		return "", nil
+	case "ZERO":
+		return "ZERO ", nil
+	case "NOT":
+		return "NOT ", nil
+	case "ZERO NOT":
+		return "NOT ZERO ", nil
+	}
+
+	return "", errors.New("Conditionals " + conditionals.ToString() + " cannot be assembled")
+}
+
+func instructionSource(instruction module.DecodedInstruction, mod module.Module, relocations fieldTable, externals fieldTable, codeLabels map[int]string) (string, error) {
+	prefix, err := conditionalsSource(instruction.Conditionals)
+	if err != nil {
+		return "", err
+	}
+
+	text := prefix + instruction.Definition.ToString()
+
+	if len(instruction.Operand) == 0 {
+		return text, nil
+	}
+
+	offset := operandOffset(instruction)
+	value := instruction.OperandValue()
+	operand := ""
+
+	switch {
+	case instruction.IsFarCall():
+		if value >= len(mod.Imports) {
+			return "", fmt.Errorf("Invalid import index %d", value)
+		}
+		operand = mod.Imports[value].Name + "." + mod.Imports[value].Value
+
+	case instruction.IsJump():
+		if name, ok := externals[offset]; ok {
+			operand = name
+		} else {
+			operand = codeLabels[value]
+		}
+
+	case instruction.Definition.AddressMode == "D":
+		operand = "@" + makeLabel("data_", value, mod.DataAddressWidth)
+
+	case instruction.Definition.AddressMode == "I":
+		operand = "@@" + makeLabel("data_", value, mod.DataAddressWidth)
+
+	case relocations[offset] == "DATA":
+		operand = makeLabel("data_", value, mod.DataAddressWidth)
+
+	default:
+		operand = strconv.Itoa(value)
+	}
+
+	return text + "\t" + operand, nil
+}
+
+func disassemble(moduleFile string, mod module.Module) ([]string, error) {
+	instructions, err := module.Disassemble(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	relocations, externals, err := makeFieldTables(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	codeLabels, err := makeCodeLabels(mod, instructions, externals)
+	if err != nil {
+		return nil, err
+	}
+
+	references := makeDataReferences(instructions, relocations)
+
+	dataSize := len(mod.DataPage.Contents)
+	bssStart := dataSize
+	constStart := bssStart + mod.BSSSize
+	dataSpaceSize := constStart + len(mod.ConstPage.Contents)
+
+	for address := range references {
+		if address >= dataSpaceSize {
+			return nil, fmt.Errorf("Data address %02X is outside the data space", address)
+		}
+	}
+
+	lines := []string{}
+
+	lines = append(lines, "# disassembled from "+filepath.Base(moduleFile))
+	options := fmt.Sprintf("# options: --code-width %d --data-width %d", mod.CodeAddressWidth, mod.DataAddressWidth)
+	lines = append(lines, options)
+	lines = append(lines, "")
+
+	externalNames := []string{}
+	declared := make(map[string]bool)
+	for _, name := range externals {
+		if !declared[name] {
+			externalNames = append(externalNames, name)
+			declared[name] = true
+		}
+	}
+	sort.Strings(externalNames)
+
+	for _, name := range externalNames {
+		lines = append(lines, "\tEXTERNAL\t"+name)
+	}
+
+	if len(externalNames) > 0 {
+		lines = append(lines, "")
+	}
+
+	declarations := []declaration{}
+	declarations = append(declarations, declareBytes(mod.DataPage.Contents, 0, references, "", mod.DataAddressWidth)...)
+	declarations = append(declarations, declareReserve(mod.BSSSize, bssStart, references, mod.DataAddressWidth)...)
+	declarations = append(declarations, declareBytes(mod.ConstPage.Contents, constStart, references, "CONST", mod.DataAddressWidth)...)
+
+	for _, decl := range declarations {
+		lines = append(lines, decl.toString())
+	}
+
+	if len(declarations) > 0 {
+		lines = append(lines, "")
+	}
+
+	for _, instruction := range instructions {
+		text, err := instructionSource(instruction, mod, relocations, externals, codeLabels)
+		if err != nil {
+			return nil, fmt.Errorf("%s at %02X", err.Error(), instruction.Address)
+		}
+
+		label := ""
+		if name, ok := codeLabels[instruction.Address]; ok {
+			label = name + ":"
+		}
+
+		lines = append(lines, label+"\t"+text)
+	}
+
+	return lines, nil
+}
+
+func main() {
+	flag.Parse()
+
+	args := flag.Args()
+
+	if len(args) == 0 {
+		fmt.Println("No module file specified")
+		os.Exit(1)
+	}
+
+	moduleFile := args[0]
+
+	mod, err := module.Read(moduleFile)
+	vputils.CheckPrintAndExit(err, "in "+moduleFile)
+
+	// store output source file name
+	sourceFile := ""
+	if len(args) > 1 {
+		sourceFile = args[1]
+	}
+
+	lines, err := disassemble(moduleFile, mod)
+	vputils.CheckAndExit(err)
+
+	source := strings.Join(lines, "\n") + "\n"
+
+	// if output specified, write source file, otherwise print it
+	if len(sourceFile) > 0 {
+		err = ioutil.WriteFile(sourceFile, []byte(source), 0644)
+		vputils.CheckAndExit(err)
+	} else {
+		fmt.Print(source)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"os"
+)
+
+func (kernel *Kernel) registerArguments() error {
+	functions := []Function{
+		{"arg_count", "arguments", []string{}, []string{"I16"}, kernel.argumentCount},
+		{"arg", "arguments", []string{"I16"}, []string{"BYTE", "STRING"}, kernel.argument},
+		{"env", "environment", []string{"STRING"}, []string{"BYTE", "STRING"}, environment},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+// SetArguments - the command line of the program: the module name, then its arguments
+func (kernel *Kernel) SetArguments(args []string) {
+	kernel.arguments = args
+}
+
+// arg_count - the number of arguments, not counting the module name
+func (kernel *Kernel) argumentCount(args []interface{}) ([]interface{}, error) {
+	count := len(kernel.arguments) - 1
+	if count < 0 {
+		count = 0
+	}
+
+	return []interface{}{int16(count)}, nil
+}
+
+// arg - an argument by number, 0 for the module name: status, argument
+func (kernel *Kernel) argument(args []interface{}) ([]interface{}, error) {
+	index := int(args[0].(int16))
+
+	if index < 0 || index >= len(kernel.arguments) || len(kernel.arguments[index]) > 255 {
+		return []interface{}{statusInvalid, ""}, nil
+	}
+
+	return []interface{}{statusOK, kernel.arguments[index]}, nil
+}
+
+// env - the value of an environment variable: status, value
+func environment(args []interface{}) ([]interface{}, error) {
+	value, ok := os.LookupEnv(args[0].(string))
+
+	if !ok || len(value) > 255 {
+		return []interface{}{statusInvalid, ""}, nil
+	}
+
+	return []interface{}{statusOK, value}, nil
+}
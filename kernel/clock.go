@@ -0,0 +1,41 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"time"
+)
+
+func (kernel *Kernel) registerClock() error {
+	functions := []Function{
+		{"time", "clock", []string{}, []string{"STRING"}, kernel.timeOfDay},
+		{"date", "clock", []string{}, []string{"STRING"}, kernel.date},
+		{"timer", "clock", []string{}, []string{"F64"}, kernel.timer},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+// SetClock - stop the clock at a time, for the same times on every run
+func (kernel *Kernel) SetClock(t time.Time) {
+	kernel.clock = func() time.Time { return t }
+}
+
+// time - the time of day, as HH:MM:SS
+func (kernel *Kernel) timeOfDay(args []interface{}) ([]interface{}, error) {
+	return []interface{}{kernel.clock().Format("15:04:05")}, nil
+}
+
+// date - the date, as MM-DD-YYYY
+func (kernel *Kernel) date(args []interface{}) ([]interface{}, error) {
+	return []interface{}{kernel.clock().Format("01-02-2006")}, nil
+}
+
+// timer - the seconds since midnight
+func (kernel *Kernel) timer(args []interface{}) ([]interface{}, error) {
+	now := kernel.clock()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	return []interface{}{now.Sub(midnight).Seconds()}, nil
+}
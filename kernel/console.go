@@ -0,0 +1,124 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// status values for input and output functions
+const (
+	statusEnd     = byte(0)
+	statusOK      = byte(1)
+	statusInvalid = byte(2)
+)
+
+func (kernel *Kernel) registerConsole() error {
+	functions := []Function{
+		{"out_b", "console", []string{"BYTE"}, []string{}, kernel.outByte},
+		{"out_s", "console", []string{"STRING"}, []string{}, kernel.outString},
+
+		{"in_b", "console", []string{}, []string{"BYTE", "BYTE"}, kernel.inByte},
+		{"in_line", "console", []string{}, []string{"BYTE", "STRING"}, kernel.inLine},
+		{"in_i16", "console", []string{}, []string{"BYTE", "I16"}, kernel.inI16},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+// Print - print to the console, within the output limit
+// all console output, from kernel calls and the OUT instruction, goes through here
+func (kernel *Kernel) Print(s string) error {
+	if kernel.outputLimit > 0 && kernel.written+len(s) > kernel.outputLimit {
+		return fmt.Errorf("Output limit of %d bytes exceeded", kernel.outputLimit)
+	}
+
+	kernel.written += len(s)
+	_, err := io.WriteString(kernel.output, s)
+
+	return err
+}
+
+// out_b - print a byte as a character
+func (kernel *Kernel) outByte(args []interface{}) ([]interface{}, error) {
+	return []interface{}{}, kernel.Print(string(args[0].(byte)))
+}
+
+// out_s - print a string
+func (kernel *Kernel) outString(args []interface{}) ([]interface{}, error) {
+	return []interface{}{}, kernel.Print(args[0].(string))
+}
+
+// read a line, without its line ending
+// the end of input is not an error, but a line cannot be empty at the end of input
+func (kernel *Kernel) readLine() (string, bool, error) {
+	line, err := kernel.input.ReadString('\n')
+	if err == io.EOF {
+		return line, len(line) > 0, nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	return line, true, nil
+}
+
+// in_b - read a byte
+func (kernel *Kernel) inByte(args []interface{}) ([]interface{}, error) {
+	b, err := kernel.input.ReadByte()
+	if err == io.EOF {
+		return []interface{}{statusEnd, byte(0)}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{statusOK, b}, nil
+}
+
+// in_line - read a line
+func (kernel *Kernel) inLine(args []interface{}) ([]interface{}, error) {
+	line, ok, err := kernel.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return []interface{}{statusEnd, ""}, nil
+	}
+
+	if len(line) > 255 {
+		return nil, errors.New("Input line too long")
+	}
+
+	return []interface{}{statusOK, line}, nil
+}
+
+// in_i16 - read a line holding a number
+func (kernel *Kernel) inI16(args []interface{}) ([]interface{}, error) {
+	line, ok, err := kernel.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return []interface{}{statusEnd, int16(0)}, nil
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(line), 10, 16)
+	if err != nil {
+		return []interface{}{statusInvalid, int16(0)}, nil
+	}
+
+	return []interface{}{statusOK, int16(value)}, nil
+}
@@ -0,0 +1,401 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// status for a file operation the system refused
+const statusFailed = byte(3)
+
+// the number of files a program can have open
+const maxFiles = 16
+
+// an open file; sequential input is buffered, random access is not
+type openFile struct {
+	file   *os.File
+	reader *bufio.Reader
+	mode   string
+}
+
+// the flags for opening a file in each mode
+var fileModes = map[string]int{
+	"INPUT":  os.O_RDONLY,
+	"OUTPUT": os.O_WRONLY | os.O_CREATE | os.O_TRUNC,
+	"APPEND": os.O_WRONLY | os.O_CREATE | os.O_APPEND,
+	"RANDOM": os.O_RDWR | os.O_CREATE,
+}
+
+func (kernel *Kernel) registerFiles() error {
+	functions := []Function{
+		{"f_open", "files", []string{"STRING", "STRING"}, []string{"BYTE", "BYTE"}, kernel.fileOpen},
+		{"f_close", "files", []string{"BYTE"}, []string{}, kernel.fileClose},
+		{"f_read_b", "files", []string{"BYTE"}, []string{"BYTE", "BYTE"}, kernel.fileReadByte},
+		{"f_read_line", "files", []string{"BYTE"}, []string{"BYTE", "STRING"}, kernel.fileReadLine},
+		{"f_write_b", "files", []string{"BYTE", "BYTE"}, []string{"BYTE"}, kernel.fileWriteByte},
+		{"f_write_s", "files", []string{"BYTE", "STRING"}, []string{"BYTE"}, kernel.fileWriteString},
+		{"f_seek", "files", []string{"BYTE", "I32"}, []string{"BYTE"}, kernel.fileSeek},
+		{"f_eof", "files", []string{"BYTE"}, []string{"BYTE"}, kernel.fileEOF},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+// SetFileDirectory - confine file access to a directory
+func (kernel *Kernel) SetFileDirectory(directory string) {
+	kernel.fileDirectory = directory
+}
+
+// CloseFiles - close the files the program left open
+func (kernel *Kernel) CloseFiles() {
+	for handle, f := range kernel.files {
+		f.file.Close()
+		delete(kernel.files, handle)
+	}
+}
+
+// is a path inside a directory
+func isInside(directory string, path string) bool {
+	relative, err := filepath.Rel(directory, path)
+
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// the path of a file, inside the file directory when there is one
+// links are followed, so a link in the directory cannot lead outside it
+func (kernel *Kernel) filePath(name string) (string, error) {
+	if len(kernel.fileDirectory) == 0 {
+		return name, nil
+	}
+
+	outside := errors.New("File " + name + " is outside the file directory")
+
+	directory, err := filepath.EvalSymlinks(kernel.fileDirectory)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(directory, name)
+	if filepath.IsAbs(name) || !isInside(directory, path) {
+		return "", outside
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// a link to nothing would be followed when the file is created
+		_, err = os.Lstat(path)
+		if !os.IsNotExist(err) {
+			return "", outside
+		}
+
+		// a new file is created in its directory, which may itself be a link
+		parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return path, nil
+		}
+
+		resolved = filepath.Join(parent, filepath.Base(path))
+	}
+
+	if !isInside(directory, resolved) {
+		return "", outside
+	}
+
+	return resolved, nil
+}
+
+// the open file for a handle
+func (kernel *Kernel) findFile(handle byte) (*openFile, error) {
+	f, ok := kernel.files[handle]
+	if !ok {
+		return nil, fmt.Errorf("File handle %d is not open", handle)
+	}
+
+	return f, nil
+}
+
+// the lowest free handle, zero if none
+func (kernel *Kernel) freeHandle() byte {
+	for handle := byte(1); int(handle) <= kernel.fileLimit; handle++ {
+		if _, ok := kernel.files[handle]; !ok {
+			return handle
+		}
+	}
+
+	return 0
+}
+
+func (f *openFile) canRead() bool {
+	return f.mode == "INPUT" || f.mode == "RANDOM"
+}
+
+func (f *openFile) canWrite() bool {
+	return f.mode != "INPUT"
+}
+
+func (f *openFile) readByte() (byte, error) {
+	if f.reader != nil {
+		return f.reader.ReadByte()
+	}
+
+	buffer := []byte{0}
+	_, err := io.ReadFull(f.file, buffer)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return buffer[0], err
+}
+
+func (f *openFile) atEnd() (bool, error) {
+	if f.reader != nil {
+		_, err := f.reader.Peek(1)
+		if err == io.EOF {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	position, err := f.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	return position >= info.Size(), nil
+}
+
+// f_open - open a file by name and mode: status, handle
+func (kernel *Kernel) fileOpen(args []interface{}) ([]interface{}, error) {
+	name := args[0].(string)
+	mode := strings.ToUpper(args[1].(string))
+
+	flags, ok := fileModes[mode]
+	if !ok {
+		return nil, errors.New("Invalid file mode " + mode)
+	}
+
+	path, err := kernel.filePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	handle := kernel.freeHandle()
+	if handle == 0 {
+		return []interface{}{statusFailed, byte(0)}, nil
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return []interface{}{statusFailed, byte(0)}, nil
+	}
+
+	f := &openFile{file, nil, mode}
+	if mode == "INPUT" {
+		f.reader = bufio.NewReader(file)
+	}
+
+	kernel.files[handle] = f
+
+	return []interface{}{statusOK, handle}, nil
+}
+
+// f_close - close a file
+func (kernel *Kernel) fileClose(args []interface{}) ([]interface{}, error) {
+	handle := args[0].(byte)
+
+	f, err := kernel.findFile(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(kernel.files, handle)
+
+	return []interface{}{}, f.file.Close()
+}
+
+// the open file for a handle, if it can be read
+func (kernel *Kernel) findInputFile(handle byte) (*openFile, error) {
+	f, err := kernel.findFile(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.canRead() {
+		return nil, fmt.Errorf("File handle %d is not open for reading", handle)
+	}
+
+	return f, nil
+}
+
+// the open file for a handle, if it can be written
+func (kernel *Kernel) findOutputFile(handle byte) (*openFile, error) {
+	f, err := kernel.findFile(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.canWrite() {
+		return nil, fmt.Errorf("File handle %d is not open for writing", handle)
+	}
+
+	return f, nil
+}
+
+// f_read_b - read a byte: status, byte
+func (kernel *Kernel) fileReadByte(args []interface{}) ([]interface{}, error) {
+	f, err := kernel.findInputFile(args[0].(byte))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := f.readByte()
+	if err == io.EOF {
+		return []interface{}{statusEnd, byte(0)}, nil
+	}
+
+	if err != nil {
+		return []interface{}{statusFailed, byte(0)}, nil
+	}
+
+	return []interface{}{statusOK, b}, nil
+}
+
+// f_read_line - read a line, without its ending: status, line
+func (kernel *Kernel) fileReadLine(args []interface{}) ([]interface{}, error) {
+	f, err := kernel.findInputFile(args[0].(byte))
+	if err != nil {
+		return nil, err
+	}
+
+	// keep no more than a string holds, with a byte over to tell a long line
+	var line bytes.Buffer
+	count := 0
+
+	b, err := f.readByte()
+	for err == nil && b != '\n' {
+		if line.Len() <= 256 {
+			line.WriteByte(b)
+		}
+
+		count++
+		b, err = f.readByte()
+	}
+
+	if err == io.EOF && count == 0 {
+		return []interface{}{statusEnd, ""}, nil
+	}
+
+	if err != nil && err != io.EOF {
+		return []interface{}{statusFailed, ""}, nil
+	}
+
+	// a line too long for a string is read, but not returned
+	s := strings.TrimSuffix(line.String(), "\r")
+	if len(s) > 255 {
+		return []interface{}{statusFailed, ""}, nil
+	}
+
+	return []interface{}{statusOK, s}, nil
+}
+
+// f_write_b - write a byte: status
+func (kernel *Kernel) fileWriteByte(args []interface{}) ([]interface{}, error) {
+	f, err := kernel.findOutputFile(args[0].(byte))
+	if err != nil {
+		return nil, err
+	}
+
+	err = kernel.countFileBytes(1)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.file.Write([]byte{args[1].(byte)})
+	if err != nil {
+		return []interface{}{statusFailed}, nil
+	}
+
+	return []interface{}{statusOK}, nil
+}
+
+// f_write_s - write a string: status
+func (kernel *Kernel) fileWriteString(args []interface{}) ([]interface{}, error) {
+	f, err := kernel.findOutputFile(args[0].(byte))
+	if err != nil {
+		return nil, err
+	}
+
+	s := args[1].(string)
+
+	err = kernel.countFileBytes(len(s))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.file.WriteString(s)
+	if err != nil {
+		return []interface{}{statusFailed}, nil
+	}
+
+	return []interface{}{statusOK}, nil
+}
+
+// f_seek - move to a byte position in a random access file: status
+func (kernel *Kernel) fileSeek(args []interface{}) ([]interface{}, error) {
+	handle := args[0].(byte)
+	position := args[1].(int32)
+
+	f, err := kernel.findFile(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.mode != "RANDOM" {
+		return nil, fmt.Errorf("File handle %d is not open for random access", handle)
+	}
+
+	if position < 0 {
+		return nil, fmt.Errorf("Invalid file position %d", position)
+	}
+
+	_, err = f.file.Seek(int64(position), io.SeekStart)
+	if err != nil {
+		return []interface{}{statusFailed}, nil
+	}
+
+	return []interface{}{statusOK}, nil
+}
+
+// f_eof - is the file at its end: 1 at the end, 0 otherwise
+func (kernel *Kernel) fileEOF(args []interface{}) ([]interface{}, error) {
+	f, err := kernel.findInputFile(args[0].(byte))
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := f.atEnd()
+	if err != nil {
+		return nil, err
+	}
+
+	if end {
+		return []interface{}{byte(1)}, nil
+	}
+
+	return []interface{}{byte(0)}, nil
+}
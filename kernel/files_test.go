@@ -0,0 +1,186 @@
+package kernel
+
+import (
+	"github.com/jfitz/virtual-processor/vputils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// a kernel confined to a new directory inside another new directory
+func makeFileKernel(t *testing.T) (*Kernel, string, string) {
+	kern := makeTestKernel(t)
+
+	outside := t.TempDir()
+	directory := filepath.Join(outside, "sandbox")
+
+	err := os.Mkdir(directory, 0755)
+	if err != nil {
+		t.Fatalf("Mkdir: %s", err)
+	}
+
+	kern.SetFileDirectory(directory)
+
+	return kern, directory, outside
+}
+
+func TestLinksCannotLeaveFileDirectory(t *testing.T) {
+	kern, directory, outside := makeFileKernel(t)
+	defer kern.CloseFiles()
+
+	secret := filepath.Join(outside, "secret.txt")
+	err := os.WriteFile(secret, []byte("secret\n"), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	err = os.Symlink(secret, filepath.Join(directory, "link.txt"))
+	if err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	err = os.Symlink(filepath.Join(outside, "new.txt"), filepath.Join(directory, "dangling.txt"))
+	if err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	err = os.Symlink(outside, filepath.Join(directory, "up"))
+	if err != nil {
+		t.Fatalf("Symlink: %s", err)
+	}
+
+	opens := []struct {
+		name string
+		mode string
+	}{
+		{"link.txt", "INPUT"},
+		{"dangling.txt", "OUTPUT"},
+		{"up/new.txt", "OUTPUT"},
+	}
+
+	for _, open := range opens {
+		_, err := kern.fileOpen([]interface{}{open.name, open.mode})
+		if err == nil {
+			t.Errorf("f_open(%s, %s) succeeded, want error", open.name, open.mode)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(outside, "new.txt"))
+	if !os.IsNotExist(err) {
+		t.Error("a link created a file outside the file directory")
+	}
+
+	results, err := kern.fileOpen([]interface{}{"notes.txt", "OUTPUT"})
+	if err != nil || results[0].(byte) != statusOK {
+		t.Errorf("f_open(notes.txt, OUTPUT) = %v %v, want status 1", results, err)
+	}
+}
+
+func TestFileBytesLimit(t *testing.T) {
+	kern, _, _ := makeFileKernel(t)
+	defer kern.CloseFiles()
+
+	if err := kern.SetFileBytesLimit(-1); err == nil {
+		t.Error("SetFileBytesLimit(-1) succeeded, want error")
+	}
+
+	err := kern.SetFileBytesLimit(6)
+	if err != nil {
+		t.Fatalf("SetFileBytesLimit: %s", err)
+	}
+
+	results, err := kern.fileOpen([]interface{}{"notes.txt", "OUTPUT"})
+	if err != nil {
+		t.Fatalf("f_open: %s", err)
+	}
+
+	handle := results[1].(byte)
+
+	if _, err = kern.fileWriteString([]interface{}{handle, "first"}); err != nil {
+		t.Fatalf("f_write_s within the limit: %s", err)
+	}
+
+	if _, err = kern.fileWriteByte([]interface{}{handle, byte(10)}); err != nil {
+		t.Fatalf("f_write_b within the limit: %s", err)
+	}
+
+	if _, err = kern.fileWriteByte([]interface{}{handle, byte(10)}); err == nil {
+		t.Error("f_write_b past the limit succeeded, want error")
+	}
+}
+
+func TestSeekPastSixteenBits(t *testing.T) {
+	kern, directory, _ := makeFileKernel(t)
+	defer kern.CloseFiles()
+
+	results, err := kern.fileOpen([]interface{}{"random.dat", "RANDOM"})
+	if err != nil {
+		t.Fatalf("f_open: %s", err)
+	}
+
+	handle := results[1].(byte)
+
+	// as KCALL sees it: the position, the handle, then the function name on top
+	vStack := vputils.ByteStack{}.PushI32(70000)
+	vStack = vStack.PushByte(handle)
+	vStack = vStack.PushString("f_seek")
+
+	vStack, err = kern.Call(vStack)
+	if err != nil {
+		t.Fatalf("f_seek: %s", err)
+	}
+
+	status, _ := vStack.TopByte()
+	if status != statusOK {
+		t.Fatalf("f_seek(70000) status %d, want 1", status)
+	}
+
+	if _, err = kern.fileWriteByte([]interface{}{handle, byte(10)}); err != nil {
+		t.Fatalf("f_write_b: %s", err)
+	}
+
+	info, err := os.Stat(filepath.Join(directory, "random.dat"))
+	if err != nil || info.Size() != 70001 {
+		t.Errorf("file size after seek and write is %v %v, want 70001", info.Size(), err)
+	}
+}
+
+func TestReadLongLine(t *testing.T) {
+	kern, directory, _ := makeFileKernel(t)
+	defer kern.CloseFiles()
+
+	contents := strings.Repeat("x", 300) + "\n" + strings.Repeat("y", 255) + "\r\nshort\n"
+	err := os.WriteFile(filepath.Join(directory, "lines.txt"), []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+
+	results, err := kern.fileOpen([]interface{}{"lines.txt", "INPUT"})
+	if err != nil {
+		t.Fatalf("f_open: %s", err)
+	}
+
+	handle := results[1].(byte)
+
+	expected := []struct {
+		status byte
+		line   string
+	}{
+		{statusFailed, ""},
+		{statusOK, strings.Repeat("y", 255)},
+		{statusOK, "short"},
+		{statusEnd, ""},
+	}
+
+	for i, want := range expected {
+		results, err := kern.fileReadLine([]interface{}{handle})
+		if err != nil {
+			t.Fatalf("f_read_line %d: %s", i, err)
+		}
+
+		if results[0].(byte) != want.status || results[1].(string) != want.line {
+			t.Errorf("f_read_line %d = %d %.10q, want %d %.10q", i, results[0], results[1], want.status, want.line)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"bufio"
+	"errors"
+	"github.com/jfitz/virtual-processor/vputils"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// Handler - the Go function behind a kernel function
+// arguments and results are byte (BYTE), int16 (I16), float64 (F64), or string (STRING)
+type Handler func(args []interface{}) ([]interface{}, error)
+
+// Function - a kernel function, with its group and stack signature
+// the first argument is on top of the stack, under the function name
+// the results are pushed so the first result is on top
+type Function struct {
+	Name    string
+	Group   string
+	Args    []string
+	Results []string
+	Handler Handler
+}
+
+// Kernel - the kernel functions a program can call, and their state
+type Kernel struct {
+	functions     map[string]Function
+	input         *bufio.Reader
+	output        io.Writer
+	files         map[byte]*openFile
+	fileDirectory string
+	generator     *rand.Rand
+	clock         func() time.Time
+	arguments     []string
+	denied        map[string]bool
+	outputLimit   int
+	written       int
+	fileLimit     int
+	fileBytes     int
+	fileWritten   int
+}
+
+// the value types a kernel function can take and return
+var valueTypes = []string{"BYTE", "I16", "I32", "F64", "STRING"}
+
+// MakeKernel - a kernel with the built-in functions
+func MakeKernel() (*Kernel, error) {
+	kernel := &Kernel{make(map[string]Function), bufio.NewReader(os.Stdin), os.Stdout, make(map[byte]*openFile), "", nil, time.Now, []string{}, make(map[string]bool), 0, 0, maxFiles, 0, 0}
+
+	registers := []func() error{
+		kernel.registerConsole,
+		kernel.registerFiles,
+		kernel.registerNumbers,
+		kernel.registerMath,
+		kernel.registerRandom,
+		kernel.registerClock,
+		kernel.registerArguments,
+	}
+
+	for _, register := range registers {
+		err := register()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	kernel.SetSeed(time.Now().UnixNano())
+
+	return kernel, nil
+}
+
+// SetInput - read console input from a reader
+func (kernel *Kernel) SetInput(r io.Reader) {
+	kernel.input = bufio.NewReader(r)
+}
+
+// SetOutput - write console output to a writer
+func (kernel *Kernel) SetOutput(w io.Writer) {
+	kernel.output = w
+}
+
+func isValueType(name string) bool {
+	for _, valueType := range valueTypes {
+		if name == valueType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Register - add a function to the kernel
+func (kernel *Kernel) Register(function Function) error {
+	if len(function.Name) == 0 {
+		return errors.New("Kernel function has no name")
+	}
+
+	if _, ok := kernel.functions[function.Name]; ok {
+		return errors.New("Kernel function '" + function.Name + "' is already registered")
+	}
+
+	if len(function.Group) == 0 {
+		return errors.New("Kernel function '" + function.Name + "' has no group")
+	}
+
+	if function.Handler == nil {
+		return errors.New("Kernel function '" + function.Name + "' has no handler")
+	}
+
+	for _, valueType := range append(function.Args, function.Results...) {
+		if !isValueType(valueType) {
+			return errors.New("Invalid type " + valueType + " for kernel function '" + function.Name + "'")
+		}
+	}
+
+	kernel.functions[function.Name] = function
+
+	return nil
+}
+
+// register a group of functions, stopping at the first that is rejected
+func (kernel *Kernel) registerAll(functions []Function) error {
+	for _, function := range functions {
+		err := kernel.Register(function)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find - find a function by name
+func (kernel *Kernel) Find(name string) (Function, bool) {
+	function, ok := kernel.functions[name]
+
+	return function, ok
+}
+
+// Names - the names of the functions, sorted
+func (kernel *Kernel) Names() []string {
+	names := []string{}
+
+	for name := range kernel.functions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Call - pop a function name and its arguments, call it, and push its results
+func (kernel *Kernel) Call(vStack vputils.ByteStack) (vputils.ByteStack, error) {
+	name, vStack, err := popValue(vStack, "STRING")
+	if err != nil {
+		return vStack, errors.New("Missing kernel function name: " + err.Error())
+	}
+
+	fname := name.(string)
+
+	function, ok := kernel.functions[fname]
+	if !ok {
+		return vStack, errors.New("Unknown kernel call to function '" + fname + "'")
+	}
+
+	if kernel.denied[function.Group] {
+		return vStack, errors.New("Kernel call to '" + fname + "' denied: group " + function.Group + " is not allowed")
+	}
+
+	args := []interface{}{}
+	for _, valueType := range function.Args {
+		var arg interface{}
+		arg, vStack, err = popValue(vStack, valueType)
+		if err != nil {
+			return vStack, errors.New(err.Error() + " in kernel call to '" + fname + "'")
+		}
+
+		args = append(args, arg)
+	}
+
+	results, err := function.Handler(args)
+	if err != nil {
+		return vStack, errors.New(err.Error() + " in kernel call to '" + fname + "'")
+	}
+
+	if len(results) != len(function.Results) {
+		return vStack, errors.New("Wrong number of results from kernel call to '" + fname + "'")
+	}
+
+	// push the last result first, so the first result is on top
+	for i := len(results) - 1; i >= 0; i-- {
+		vStack, err = pushValue(vStack, function.Results[i], results[i])
+		if err != nil {
+			return vStack, errors.New(err.Error() + " from kernel call to '" + fname + "'")
+		}
+	}
+
+	return vStack, nil
+}
+
+// pop a value of a type from the stack
+func popValue(vStack vputils.ByteStack, valueType string) (interface{}, vputils.ByteStack, error) {
+	switch valueType {
+	case "BYTE":
+		bytes, vStack, err := vStack.PopByte(1)
+		if err != nil {
+			return nil, vStack, err
+		}
+
+		return bytes[0], vStack, nil
+
+	case "I16":
+		value, vStack, err := vStack.PopI16()
+		if err != nil {
+			return nil, vStack, err
+		}
+
+		return value, vStack, nil
+
+	case "I32":
+		value, vStack, err := vStack.PopI32()
+		if err != nil {
+			return nil, vStack, err
+		}
+
+		return value, vStack, nil
+
+	case "F64":
+		value, vStack, err := vStack.PopF64()
+		if err != nil {
+			return nil, vStack, err
+		}
+
+		return value, vStack, nil
+
+	case "STRING":
+		counts, vStack, err := vStack.PopByte(1)
+		if err != nil {
+			return nil, vStack, err
+		}
+
+		bytes, vStack, err := vStack.PopByte(int(counts[0]))
+		if err != nil {
+			return nil, vStack, err
+		}
+
+		// the first character is on top, and NUL bytes are not text
+		s := ""
+		for i := len(bytes); i > 0; i-- {
+			if bytes[i-1] != 0 {
+				s += string(bytes[i-1])
+			}
+		}
+
+		return s, vStack, nil
+	}
+
+	return nil, vStack, errors.New("Invalid type " + valueType)
+}
+
+// push a value of a type on the stack
+func pushValue(vStack vputils.ByteStack, valueType string, value interface{}) (vputils.ByteStack, error) {
+	switch valueType {
+	case "BYTE":
+		b, ok := value.(byte)
+		if ok {
+			return vStack.PushByte(b), nil
+		}
+
+	case "I16":
+		i, ok := value.(int16)
+		if ok {
+			return vStack.PushI16(i), nil
+		}
+
+	case "I32":
+		i, ok := value.(int32)
+		if ok {
+			return vStack.PushI32(i), nil
+		}
+
+	case "F64":
+		f, ok := value.(float64)
+		if ok {
+			return vStack.PushF64(f), nil
+		}
+
+	case "STRING":
+		s, ok := value.(string)
+		if ok {
+			if len(s) > 255 {
+				return vStack, errors.New("String too long")
+			}
+
+			return vStack.PushString(s), nil
+		}
+	}
+
+	return vStack, errors.New("Result is not " + valueType)
+}
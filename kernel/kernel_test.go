@@ -0,0 +1,131 @@
+package kernel
+
+import (
+	"bytes"
+	"github.com/jfitz/virtual-processor/vputils"
+	"testing"
+)
+
+func twice(args []interface{}) ([]interface{}, error) {
+	return []interface{}{args[0].(byte) * 2}, nil
+}
+
+func makeTestKernel(t *testing.T) *Kernel {
+	kern, err := MakeKernel()
+	if err != nil {
+		t.Fatalf("MakeKernel: %s", err)
+	}
+
+	return kern
+}
+
+func TestRegisterRejectsInvalidFunctions(t *testing.T) {
+	kern := makeTestKernel(t)
+
+	invalids := []Function{
+		{"out_b", "console", []string{"BYTE"}, []string{}, twice},
+		{"", "host", []string{"BYTE"}, []string{"BYTE"}, twice},
+		{"twice", "", []string{"BYTE"}, []string{"BYTE"}, twice},
+		{"twice", "host", []string{"BYTE"}, []string{"BYTE"}, nil},
+		{"twice", "host", []string{"WORD"}, []string{"BYTE"}, twice},
+	}
+
+	for _, function := range invalids {
+		err := kern.Register(function)
+		if err == nil {
+			t.Errorf("Register(%q, %q) succeeded, want error", function.Name, function.Group)
+		}
+	}
+}
+
+func TestCallHostFunction(t *testing.T) {
+	kern := makeTestKernel(t)
+
+	err := kern.Register(Function{"twice", "host", []string{"BYTE"}, []string{"BYTE"}, twice})
+	if err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+
+	// as KCALL sees it: the argument, then the function name on top
+	vStack := vputils.ByteStack{}.PushByte(0x15)
+	vStack = vStack.PushString("twice")
+
+	vStack, err = kern.Call(vStack)
+	if err != nil {
+		t.Fatalf("Call: %s", err)
+	}
+
+	result, err := vStack.TopByte()
+	if err != nil || result != 0x2A {
+		t.Errorf("twice(15) = %02X %v, want 2A", result, err)
+	}
+
+	err = kern.Deny("host")
+	if err != nil {
+		t.Fatalf("Deny: %s", err)
+	}
+
+	vStack = vStack.PushString("twice")
+	if _, err = kern.Call(vStack); err == nil {
+		t.Error("call to a denied host function succeeded, want error")
+	}
+}
+
+func TestBuiltInsUseConsoleOutput(t *testing.T) {
+	kern := makeTestKernel(t)
+
+	var output bytes.Buffer
+	kern.SetOutput(&output)
+
+	vStack := vputils.ByteStack{}.PushString("Hello")
+	vStack = vStack.PushString("out_s")
+
+	_, err := kern.Call(vStack)
+	if err != nil {
+		t.Fatalf("Call: %s", err)
+	}
+
+	if output.String() != "Hello" {
+		t.Errorf("out_s wrote %q, want Hello", output.String())
+	}
+}
+
+func TestMathStatus(t *testing.T) {
+	kern := makeTestKernel(t)
+
+	cases := []struct {
+		name   string
+		value  float64
+		status byte
+		result float64
+	}{
+		{"sqr", 16, statusOK, 4},
+		{"sqr", -1, statusInvalid, 0},
+		{"log", 0, statusInvalid, 0},
+		{"exp", 1000, statusInvalid, 0},
+	}
+
+	for _, c := range cases {
+		vStack := vputils.ByteStack{}.PushF64(c.value)
+		vStack = vStack.PushString(c.name)
+
+		vStack, err := kern.Call(vStack)
+		if err != nil {
+			t.Fatalf("%s(%g): %s", c.name, c.value, err)
+		}
+
+		status, vStack, err := popValue(vStack, "BYTE")
+		if err != nil {
+			t.Fatalf("%s(%g) status: %s", c.name, c.value, err)
+		}
+
+		result, _, err := popValue(vStack, "F64")
+		if err != nil {
+			t.Fatalf("%s(%g) result: %s", c.name, c.value, err)
+		}
+
+		if status.(byte) != c.status || result.(float64) != c.result {
+			t.Errorf("%s(%g) = %d %g, want %d %g", c.name, c.value, status, result, c.status, c.result)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"math"
+)
+
+// tells whether a math function accepts an argument, nil when any number is valid
+type domainCheck func(value float64) bool
+
+func (kernel *Kernel) registerMath() error {
+	functions := []Function{
+		{"sqr", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Sqrt, notNegative)},
+		{"sin", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Sin, nil)},
+		{"cos", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Cos, nil)},
+		{"tan", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Tan, nil)},
+		{"atn", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Atan, nil)},
+		{"log", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Log, positive)},
+		{"exp", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Exp, nil)},
+		{"abs", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Abs, nil)},
+		{"int", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(math.Floor, nil)},
+		{"sgn", "math", []string{"F64"}, []string{"BYTE", "F64"}, mathFunction(sign, nil)},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+func notNegative(value float64) bool {
+	return value >= 0
+}
+
+func positive(value float64) bool {
+	return value > 0
+}
+
+// SGN: -1, 0, or 1
+func sign(value float64) float64 {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+
+	return 0
+}
+
+// a handler for a function of one number: status, result
+// an argument outside the domain, or a result too large to hold,
+// gives status 2 and zero, so the program can test for it
+func mathFunction(function func(float64) float64, check domainCheck) Handler {
+	return func(args []interface{}) ([]interface{}, error) {
+		value := args[0].(float64)
+
+		if check != nil && !check(value) {
+			return []interface{}{statusInvalid, float64(0)}, nil
+		}
+
+		result := function(value)
+		if math.IsNaN(result) || math.IsInf(result, 0) {
+			return []interface{}{statusInvalid, float64(0)}, nil
+		}
+
+		return []interface{}{statusOK, result}, nil
+	}
+}
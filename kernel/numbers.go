@@ -0,0 +1,229 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (kernel *Kernel) registerNumbers() error {
+	functions := []Function{
+		{"out_i16", "numbers", []string{"I16"}, []string{}, kernel.outI16},
+		{"out_f64", "numbers", []string{"F64"}, []string{}, kernel.outF64},
+		{"out_using", "numbers", []string{"STRING", "F64"}, []string{}, kernel.outUsing},
+
+		{"i16_to_f64", "numbers", []string{"I16"}, []string{"F64"}, i16ToF64},
+		{"f64_to_i16", "numbers", []string{"F64"}, []string{"I16"}, f64ToI16},
+		{"f64_to_s", "numbers", []string{"F64"}, []string{"STRING"}, f64ToString},
+		{"s_to_f64", "numbers", []string{"STRING"}, []string{"BYTE", "F64"}, stringToF64},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+// the digits of a number, as BASIC prints them
+// up to nine significant digits, and no zero before the decimal point
+func formatNumber(value float64) string {
+	if value == 0 {
+		return "0"
+	}
+
+	s := strconv.FormatFloat(value, 'G', 9, 64)
+
+	if strings.HasPrefix(s, "0.") {
+		s = s[1:]
+	}
+
+	if strings.HasPrefix(s, "-0.") {
+		s = "-" + s[2:]
+	}
+
+	return s
+}
+
+// a number with a sign position, a space when it is not negative
+func signedNumber(value float64) string {
+	s := formatNumber(value)
+
+	if !strings.HasPrefix(s, "-") {
+		s = " " + s
+	}
+
+	return s
+}
+
+// the field of a PRINT USING mask: start and end positions
+// a field is #, comma, and decimal point, with an optional leading + or trailing -
+func findField(mask string) (int, int, error) {
+	start := strings.Index(mask, "#")
+	if start < 0 {
+		return 0, 0, errors.New("Invalid format mask '" + mask + "'")
+	}
+
+	for start > 0 && strings.ContainsRune(",.", rune(mask[start-1])) {
+		start--
+	}
+
+	if start > 0 && mask[start-1] == '+' {
+		start--
+	}
+
+	end := start + 1
+	for end < len(mask) && strings.ContainsRune("#,.", rune(mask[end])) {
+		end++
+	}
+
+	if end < len(mask) && mask[end] == '-' {
+		end++
+	}
+
+	return start, end, nil
+}
+
+// insert a comma between each group of three digits
+func groupThousands(digits string) string {
+	s := ""
+
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			s += ","
+		}
+		s += string(c)
+	}
+
+	return s
+}
+
+// format a number with a PRINT USING mask
+// a number too wide for the field is printed whole, after a %
+func formatUsing(mask string, value float64) (string, error) {
+	start, end, err := findField(mask)
+	if err != nil {
+		return "", err
+	}
+
+	field := mask[start:end]
+
+	leadingPlus := strings.HasPrefix(field, "+")
+	trailingMinus := strings.HasSuffix(field, "-")
+	field = strings.TrimPrefix(field, "+")
+	field = strings.TrimSuffix(field, "-")
+
+	intField := field
+	fracField := ""
+	point := strings.Index(field, ".")
+	if point >= 0 {
+		intField = field[:point]
+		fracField = field[point+1:]
+	}
+
+	decimals := strings.Count(fracField, "#")
+	width := len(intField)
+
+	digits := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	intDigits := digits
+	fracDigits := ""
+	if decimals > 0 {
+		intDigits = digits[:len(digits)-decimals-1]
+		fracDigits = digits[len(digits)-decimals:]
+	}
+
+	if intDigits == "0" && width == 0 {
+		intDigits = ""
+	}
+
+	if strings.Contains(intField, ",") {
+		intDigits = groupThousands(intDigits)
+	}
+
+	negative := value < 0 && strings.Trim(digits, "0.") != ""
+
+	sign := ""
+	suffix := ""
+	switch {
+	case leadingPlus && negative:
+		sign = "-"
+		width++
+	case leadingPlus:
+		sign = "+"
+		width++
+	case trailingMinus && negative:
+		suffix = "-"
+	case trailingMinus:
+		suffix = " "
+	case negative:
+		sign = "-"
+	}
+
+	number := sign + intDigits
+	if len(number) > width {
+		number = "%" + number
+	} else {
+		number = strings.Repeat(" ", width-len(number)) + number
+	}
+
+	if point >= 0 {
+		number += "." + fracDigits
+	}
+
+	return mask[:start] + number + suffix + mask[end:], nil
+}
+
+// out_i16 - print an integer, with a sign position and a trailing space
+func (kernel *Kernel) outI16(args []interface{}) ([]interface{}, error) {
+	value := float64(args[0].(int16))
+
+	return []interface{}{}, kernel.Print(signedNumber(value) + " ")
+}
+
+// out_f64 - print a number, with a sign position and a trailing space
+func (kernel *Kernel) outF64(args []interface{}) ([]interface{}, error) {
+	value := args[0].(float64)
+
+	return []interface{}{}, kernel.Print(signedNumber(value) + " ")
+}
+
+// out_using - print a number with a format mask
+func (kernel *Kernel) outUsing(args []interface{}) ([]interface{}, error) {
+	s, err := formatUsing(args[0].(string), args[1].(float64))
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{}, kernel.Print(s)
+}
+
+// i16_to_f64 - convert an integer to a number
+func i16ToF64(args []interface{}) ([]interface{}, error) {
+	return []interface{}{float64(args[0].(int16))}, nil
+}
+
+// f64_to_i16 - convert a number to the nearest integer
+func f64ToI16(args []interface{}) ([]interface{}, error) {
+	value := math.Floor(args[0].(float64) + 0.5)
+
+	if value < math.MinInt16 || value > math.MaxInt16 || math.IsNaN(value) {
+		return nil, errors.New("Overflow")
+	}
+
+	return []interface{}{int16(value)}, nil
+}
+
+// f64_to_s - convert a number to a string, with a sign position
+func f64ToString(args []interface{}) ([]interface{}, error) {
+	return []interface{}{signedNumber(args[0].(float64))}, nil
+}
+
+// s_to_f64 - convert a string to a number: status, number
+func stringToF64(args []interface{}) ([]interface{}, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(args[0].(string)), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return []interface{}{statusInvalid, float64(0)}, nil
+	}
+
+	return []interface{}{statusOK, value}, nil
+}
@@ -0,0 +1,35 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"math/rand"
+)
+
+func (kernel *Kernel) registerRandom() error {
+	functions := []Function{
+		{"rnd", "random", []string{}, []string{"F64"}, kernel.random},
+		{"randomize", "random", []string{"F64"}, []string{}, kernel.randomize},
+	}
+
+	return kernel.registerAll(functions)
+}
+
+// SetSeed - start the random numbers from a seed, for the same numbers on every run
+func (kernel *Kernel) SetSeed(seed int64) {
+	kernel.generator = rand.New(rand.NewSource(seed))
+}
+
+// rnd - the next random number, from 0 up to but not including 1
+func (kernel *Kernel) random(args []interface{}) ([]interface{}, error) {
+	return []interface{}{kernel.generator.Float64()}, nil
+}
+
+// randomize - start the random numbers from a seed
+// the whole part of the number is the seed, so RANDOMIZE TIMER works
+func (kernel *Kernel) randomize(args []interface{}) ([]interface{}, error) {
+	kernel.SetSeed(int64(args[0].(float64)))
+
+	return []interface{}{}, nil
+}
@@ -0,0 +1,111 @@
+/*
+Package kernel for virtual-processor
+*/
+package kernel
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Groups - the names of the function groups, sorted
+func (kernel *Kernel) Groups() []string {
+	groups := []string{}
+	seen := make(map[string]bool)
+
+	for _, function := range kernel.functions {
+		if !seen[function.Group] {
+			groups = append(groups, function.Group)
+			seen[function.Group] = true
+		}
+	}
+
+	sort.Strings(groups)
+
+	return groups
+}
+
+func (kernel *Kernel) isGroup(group string) bool {
+	for _, function := range kernel.functions {
+		if function.Group == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Deny - deny the functions of a group; a call to one stops the program
+func (kernel *Kernel) Deny(group string) error {
+	if !kernel.isGroup(group) {
+		return errors.New("Unknown kernel function group " + group)
+	}
+
+	kernel.denied[group] = true
+
+	return nil
+}
+
+// AllowOnly - deny the functions of every group but these
+func (kernel *Kernel) AllowOnly(groups []string) error {
+	for _, group := range groups {
+		if !kernel.isGroup(group) {
+			return errors.New("Unknown kernel function group " + group)
+		}
+	}
+
+	for _, group := range kernel.Groups() {
+		kernel.denied[group] = true
+	}
+
+	for _, group := range groups {
+		delete(kernel.denied, group)
+	}
+
+	return nil
+}
+
+// SetOutputLimit - limit the bytes printed to the console, zero for no limit
+func (kernel *Kernel) SetOutputLimit(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("Invalid output limit %d", limit)
+	}
+
+	kernel.outputLimit = limit
+
+	return nil
+}
+
+// SetFileLimit - limit the files a program can have open at once
+func (kernel *Kernel) SetFileLimit(limit int) error {
+	if limit < 0 || limit > maxFiles {
+		return fmt.Errorf("Invalid file limit %d, must be 0 to %d", limit, maxFiles)
+	}
+
+	kernel.fileLimit = limit
+
+	return nil
+}
+
+// SetFileBytesLimit - limit the bytes written to files, zero for no limit
+func (kernel *Kernel) SetFileBytesLimit(limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("Invalid file bytes limit %d", limit)
+	}
+
+	kernel.fileBytes = limit
+
+	return nil
+}
+
+// count bytes to be written to files against the limit
+func (kernel *Kernel) countFileBytes(count int) error {
+	if kernel.fileBytes > 0 && kernel.fileWritten+count > kernel.fileBytes {
+		return fmt.Errorf("File output limit of %d bytes exceeded", kernel.fileBytes)
+	}
+
+	kernel.fileWritten += count
+
+	return nil
+}
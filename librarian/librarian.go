@@ -0,0 +1,120 @@
+/*
+Package main of librarian
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jfitz/virtual-processor/module"
+	"github.com/jfitz/virtual-processor/vputils"
+	"os"
+	"path/filepath"
+)
+
+func createArchive(archiveFile string, moduleFiles []string) {
+	members := []module.Member{}
+	for _, moduleFile := range moduleFiles {
+		mod, err := module.Read(moduleFile)
+		vputils.CheckPrintAndExit(err, "in "+moduleFile)
+
+		members = append(members, module.Member{filepath.Base(moduleFile), mod})
+	}
+
+	archive, err := module.MakeArchive(members)
+	vputils.CheckAndExit(err)
+
+	err = archive.Write(archiveFile)
+	vputils.CheckAndExit(err)
+}
+
+func listArchive(archive module.Archive) {
+	tabs := "\t\t\t"
+
+	fmt.Println(tabs + "MEMBERS")
+	for _, member := range archive.Members {
+		fmt.Printf("%s\t%d\n", member.Name, len(member.Module.Exports))
+	}
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+
+	fmt.Println(tabs + "INDEX")
+	for _, nameValue := range archive.Index {
+		fmt.Printf("%s\t%s\n", nameValue.Name, nameValue.Value)
+	}
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+}
+
+func extractArchive(archive module.Archive, names []string, directory string) {
+	// no names extracts every member
+	if len(names) == 0 {
+		for _, member := range archive.Members {
+			names = append(names, member.Name)
+		}
+	}
+
+	for _, name := range names {
+		err := module.CheckMemberName(name)
+		vputils.CheckAndExit(err)
+
+		i, ok := archive.FindMember(name)
+		if !ok {
+			fmt.Println("Member " + name + " not found")
+			os.Exit(1)
+		}
+
+		moduleFile := filepath.Join(directory, name)
+		err = archive.Members[i].Module.Write(moduleFile)
+		vputils.CheckAndExit(err)
+
+		fmt.Println("Extracted " + name)
+	}
+}
+
+func main() {
+	directoryPtr := flag.String("directory", ".", "Extract members into directory.")
+
+	flag.Parse()
+
+	directory := *directoryPtr
+
+	args := flag.Args()
+
+	if len(args) < 2 {
+		fmt.Println("Usage: librarian [options] create|list|extract archive [modules]")
+		os.Exit(1)
+	}
+
+	command := args[0]
+	archiveFile := args[1]
+	names := args[2:]
+
+	switch command {
+
+	case "create":
+		if len(names) == 0 {
+			fmt.Println("No module files specified")
+			os.Exit(1)
+		}
+
+		createArchive(archiveFile, names)
+
+	case "list":
+		archive, err := module.ReadArchive(archiveFile)
+		vputils.CheckPrintAndExit(err, "in "+archiveFile)
+
+		listArchive(archive)
+
+	case "extract":
+		archive, err := module.ReadArchive(archiveFile)
+		vputils.CheckPrintAndExit(err, "in "+archiveFile)
+
+		extractArchive(archive, names, directory)
+
+	default:
+		fmt.Println("Unknown command " + command)
+		os.Exit(1)
+
+	}
+}
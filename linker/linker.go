@@ -0,0 +1,459 @@
+/*
+Package main of linker
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jfitz/virtual-processor/module"
+	"github.com/jfitz/virtual-processor/vputils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// placement of one input module in the linked module
+type placement struct {
+	Name   string
+	Module module.Module
+	module.Layout
+}
+
+type symbol struct {
+	Origin  string
+	Address int
+}
+
+// list external symbols not exported by any of the modules
+func undefinedSymbols(mods []module.Module) []string {
+	defined := make(map[string]bool)
+	for _, mod := range mods {
+		for _, export := range mod.Exports {
+			defined[export.Name] = true
+		}
+	}
+
+	undefined := []string{}
+	for _, mod := range mods {
+		for _, external := range mod.Externals {
+			if !defined[external.Name] {
+				undefined = append(undefined, external.Name)
+				defined[external.Name] = true
+			}
+		}
+	}
+
+	return undefined
+}
+
+// add the archive members that satisfy undefined symbols
+// members may have externals of their own, so repeat until nothing is added
+func selectMembers(names []string, mods []module.Module, archiveFiles []string, archives []module.Archive) ([]string, []module.Module) {
+	included := make(map[string]bool)
+
+	added := true
+	for added {
+		added = false
+
+		for _, symbol := range undefinedSymbols(mods) {
+			for j, archive := range archives {
+				i, ok := archive.FindSymbol(symbol)
+				if !ok {
+					continue
+				}
+
+				member := archive.Members[i]
+				name := filepath.Base(archiveFiles[j]) + "(" + member.Name + ")"
+				if !included[name] {
+					names = append(names, name)
+					mods = append(mods, member.Module)
+					included[name] = true
+					added = true
+				}
+
+				break
+			}
+		}
+	}
+
+	return names, mods
+}
+
+// lay out the modules, code in order and data space in three groups
+func placeModules(names []string, mods []module.Module) []placement {
+	places := []placement{}
+
+	codeBase := 0
+	dataBase := 0
+	for i, mod := range mods {
+		layout := module.Layout{CodeBase: codeBase, DataBase: dataBase}
+		place := placement{Name: filepath.Base(names[i]), Module: mod, Layout: layout}
+		places = append(places, place)
+
+		codeBase += len(mod.CodePage.Contents)
+		dataBase += len(mod.DataPage.Contents)
+	}
+
+	bssBase := dataBase
+	for i := range places {
+		places[i].BSSBase = bssBase
+		bssBase += places[i].Module.BSSSize
+	}
+
+	constBase := bssBase
+	for i := range places {
+		places[i].ConstBase = constBase
+		constBase += len(places[i].Module.ConstPage.Contents)
+	}
+
+	return places
+}
+
+// collect exports from all modules, reporting duplicates
+func collectSymbols(places []placement) (map[string]symbol, []string) {
+	symbols := make(map[string]symbol)
+	problems := []string{}
+
+	for _, place := range places {
+		for _, export := range place.Module.Exports {
+			address, err := strconv.Atoi(export.Value)
+			if err != nil {
+				problems = append(problems, "Invalid address for symbol "+export.Name+" in "+place.Name)
+				continue
+			}
+
+			if previous, ok := symbols[export.Name]; ok {
+				problems = append(problems, "Duplicate symbol "+export.Name+" in "+previous.Origin+" and "+place.Name)
+				continue
+			}
+
+			symbols[export.Name] = symbol{place.Name, place.RelocateCode(address)}
+		}
+	}
+
+	return symbols, problems
+}
+
+// check that the processor can execute the modules
+func checkInstructionSets(names []string, mods []module.Module) []string {
+	problems := []string{}
+
+	for i, mod := range mods {
+		err := mod.CheckInstructionSet()
+		if err != nil {
+			problems = append(problems, err.Error()+" in "+filepath.Base(names[i]))
+		}
+	}
+
+	return problems
+}
+
+// the sizes of the linked code and data space
+func linkedSizes(places []placement) (int, int) {
+	last := places[len(places)-1]
+	codeSize := last.CodeBase + len(last.Module.CodePage.Contents)
+	dataSize := last.ConstBase + len(last.Module.ConstPage.Contents)
+
+	return codeSize, dataSize
+}
+
+// lay out the modules with one code and one data address width
+// the widths are the widest of the modules, wider still if the linked segments need it,
+// and modules with narrower addresses are widened
+func placeWidened(names []string, mods []module.Module) ([]placement, int, int, []string) {
+	codeAddressWidth := 1
+	dataAddressWidth := 1
+
+	for _, mod := range mods {
+		if mod.CodeAddressWidth > codeAddressWidth {
+			codeAddressWidth = mod.CodeAddressWidth
+		}
+
+		if mod.DataAddressWidth > dataAddressWidth {
+			dataAddressWidth = mod.DataAddressWidth
+		}
+	}
+
+	for {
+		problems := []string{}
+		widened := []module.Module{}
+
+		for i, mod := range mods {
+			wide, err := mod.Widen(codeAddressWidth, dataAddressWidth)
+			if err != nil {
+				problems = append(problems, err.Error()+" in "+filepath.Base(names[i]))
+				wide = mod
+			}
+
+			widened = append(widened, wide)
+		}
+
+		places := placeModules(names, widened)
+		if len(problems) > 0 {
+			return places, codeAddressWidth, dataAddressWidth, problems
+		}
+
+		// wider code addresses make the code longer, so check again after widening
+		codeSize, dataSize := linkedSizes(places)
+		if vputils.AddressWidth(codeSize) <= codeAddressWidth && vputils.AddressWidth(dataSize) <= dataAddressWidth {
+			return places, codeAddressWidth, dataAddressWidth, problems
+		}
+
+		if vputils.AddressWidth(codeSize) > codeAddressWidth {
+			codeAddressWidth = vputils.AddressWidth(codeSize)
+		}
+
+		if vputils.AddressWidth(dataSize) > dataAddressWidth {
+			dataAddressWidth = vputils.AddressWidth(dataSize)
+		}
+	}
+}
+
+// merge the imports of all modules, and map each module's import indexes
+func mergeImports(places []placement) ([]vputils.NameValue, [][]int) {
+	imports := []vputils.NameValue{}
+	indexes := make(map[vputils.NameValue]int)
+	remaps := [][]int{}
+
+	for _, place := range places {
+		remap := []int{}
+
+		for _, nameValue := range place.Module.Imports {
+			index, ok := indexes[nameValue]
+			if !ok {
+				index = len(imports)
+				indexes[nameValue] = index
+				imports = append(imports, nameValue)
+			}
+
+			remap = append(remap, index)
+		}
+
+		remaps = append(remaps, remap)
+	}
+
+	return imports, remaps
+}
+
+// relocate the code of one module and resolve its external references
+func linkCode(place placement, symbols map[string]symbol, remap []int) (vputils.Vector, []vputils.NameValue, []string) {
+	rebased, err := place.Module.Rebase(place.Layout)
+	if err != nil {
+		return place.Module.CodePage.Contents, []vputils.NameValue{}, []string{err.Error() + " in " + place.Name}
+	}
+
+	code := rebased.CodePage.Contents
+
+	relocations := []vputils.NameValue{}
+	problems := []string{}
+
+	for _, relocation := range rebased.Relocations {
+		parsed, _ := module.ParseRelocation(relocation)
+
+		offset := parsed.Offset
+		width := parsed.Width
+
+		// the merged imports have new indexes
+		if parsed.Segment == "IMPORT" {
+			value, err := module.ReadField(code, offset, width)
+			if err != nil {
+				problems = append(problems, err.Error()+" in "+place.Name)
+				continue
+			}
+
+			if value >= len(remap) {
+				problems = append(problems, "Invalid import index "+strconv.Itoa(value)+" in "+place.Name)
+				continue
+			}
+
+			err = module.WriteField(code, offset, width, remap[value])
+			if err != nil {
+				problems = append(problems, err.Error()+" in "+place.Name)
+				continue
+			}
+		}
+
+		moved := module.MakeRelocation(place.RelocateCode(offset), parsed.Segment, width)
+		relocations = append(relocations, moved.ToNameValue())
+	}
+
+	width := place.Module.CodeAddressWidth
+
+	for _, external := range place.Module.Externals {
+		offset, err := strconv.Atoi(external.Value)
+		if err != nil {
+			problems = append(problems, "Invalid reference to "+external.Name+" in "+place.Name)
+			continue
+		}
+
+		target, ok := symbols[external.Name]
+		if !ok {
+			problems = append(problems, "Undefined symbol "+external.Name+" in "+place.Name)
+			continue
+		}
+
+		err = module.WriteField(code, offset, width, target.Address)
+		if err != nil {
+			problems = append(problems, err.Error()+" in "+place.Name)
+			continue
+		}
+
+		// the resolved address is a code address in the linked module
+		resolved := module.MakeRelocation(place.RelocateCode(offset), "CODE", width)
+		relocations = append(relocations, resolved.ToNameValue())
+	}
+
+	return code, relocations, problems
+}
+
+// put relocations in address order, as the assembler writes them
+// the linked module then reassembles to the same bytes
+func sortRelocations(relocations []vputils.NameValue) {
+	offset := func(i int) int {
+		value, _ := strconv.Atoi(relocations[i].Name)
+		return value
+	}
+
+	sort.SliceStable(relocations, func(i, j int) bool { return offset(i) < offset(j) })
+}
+
+func printPlacements(places []placement, codeAddressWidth int, dataAddressWidth int) {
+	tabs := "\t\t\t"
+	fmt.Println(tabs + "MODULES")
+
+	codeFormat := vputils.AddressFormat(codeAddressWidth)
+	dataFormat := vputils.AddressFormat(dataAddressWidth)
+
+	for _, place := range places {
+		fmt.Printf("%s:\n", place.Name)
+		fmt.Printf("\tCODE\t"+codeFormat+"\n", place.CodeBase)
+		fmt.Printf("\tDATA\t"+dataFormat+"\n", place.DataBase)
+		fmt.Printf("\tBSS\t"+dataFormat+"\n", place.BSSBase)
+		fmt.Printf("\tCONST\t"+dataFormat+"\n", place.ConstBase)
+	}
+
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+}
+
+func main() {
+	outputPtr := flag.String("output", "", "Write linked module to file.")
+
+	flag.Parse()
+
+	moduleFile := *outputPtr
+
+	names := []string{}
+	mods := []module.Module{}
+	archiveFiles := []string{}
+	archives := []module.Archive{}
+
+	for _, name := range flag.Args() {
+		if filepath.Ext(name) == ".lib" {
+			archive, err := module.ReadArchive(name)
+			vputils.CheckPrintAndExit(err, "in "+name)
+			archiveFiles = append(archiveFiles, name)
+			archives = append(archives, archive)
+		} else {
+			mod, err := module.Read(name)
+			vputils.CheckPrintAndExit(err, "in "+name)
+			names = append(names, name)
+			mods = append(mods, mod)
+		}
+	}
+
+	if len(mods) == 0 {
+		fmt.Println("No module files specified")
+		os.Exit(1)
+	}
+
+	// libraries contribute only the members that are needed
+	names, mods = selectMembers(names, mods, archiveFiles, archives)
+
+	problems := checkInstructionSets(names, mods)
+
+	places, codeAddressWidth, dataAddressWidth, widthProblems := placeWidened(names, mods)
+	problems = append(problems, widthProblems...)
+
+	bssStart := places[0].BSSBase
+	constStart := places[0].ConstBase
+
+	symbols, symbolProblems := collectSymbols(places)
+	problems = append(problems, symbolProblems...)
+
+	imports, remaps := mergeImports(places)
+	if len(imports) > 256 {
+		problems = append(problems, "Too many imported symbols")
+	}
+
+	// build the linked segments
+	code := vputils.Vector{}
+	data := vputils.Vector{}
+	consts := vputils.Vector{}
+	relocations := []vputils.NameValue{}
+
+	for i, place := range places {
+		linkedCode, linkedRelocations, codeProblems := linkCode(place, symbols, remaps[i])
+		problems = append(problems, codeProblems...)
+
+		code = append(code, linkedCode...)
+		data = append(data, place.Module.DataPage.Contents...)
+		consts = append(consts, place.Module.ConstPage.Contents...)
+		relocations = append(relocations, linkedRelocations...)
+	}
+
+	sortRelocations(relocations)
+
+	// report all problems together
+	if len(problems) > 0 {
+		fmt.Println("Errors found:")
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		os.Exit(1)
+	}
+
+	// exports, sorted so the module contents do not depend on map order
+	exportNames := []string{}
+	for name := range symbols {
+		exportNames = append(exportNames, name)
+	}
+	sort.Strings(exportNames)
+
+	exports := []vputils.NameValue{}
+	for _, name := range exportNames {
+		address := strconv.Itoa(symbols[name].Address)
+		exports = append(exports, vputils.NameValue{name, address})
+	}
+
+	printPlacements(places, codeAddressWidth, dataAddressWidth)
+	module.PrintExports(exports, codeAddressWidth)
+
+	first := places[0].Module
+
+	codePage := module.Page{first.CodePage.Properties, code, codeAddressWidth, 0}
+	dataPage := module.Page{first.DataPage.Properties, data, dataAddressWidth, 0}
+	constPage := module.Page{first.ConstPage.Properties, consts, dataAddressWidth, len(consts)}
+
+	mod := module.Module{
+		Properties:       first.Properties,
+		CodePage:         codePage,
+		Exports:          exports,
+		Externals:        []vputils.NameValue{},
+		Imports:          imports,
+		Relocations:      relocations,
+		DataPage:         dataPage,
+		BSSSize:          constStart - bssStart,
+		ConstPage:        constPage,
+		CodeAddressWidth: codeAddressWidth,
+		DataAddressWidth: dataAddressWidth,
+	}
+
+	// if output specified, write module file
+	if len(moduleFile) > 0 {
+		err := mod.Write(moduleFile)
+		vputils.CheckAndExit(err)
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/jfitz/virtual-processor/vputils"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Member - a module stored in an archive
+type Member struct {
+	Name   string
+	Module Module
+}
+
+// Archive - a library of modules, with an index of exported symbols
+type Archive struct {
+	Index   []vputils.NameValue
+	Members []Member
+}
+
+// CheckMemberName - check that a member name is a plain file name
+// members are extracted by name, so a name must not reach outside the directory
+func CheckMemberName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return errors.New("Invalid member name '" + name + "'")
+	}
+
+	if strings.ContainsAny(name, "/\\") {
+		return errors.New("Member name '" + name + "' contains a path separator")
+	}
+
+	return nil
+}
+
+// MakeArchive - build an archive and its symbol index
+func MakeArchive(members []Member) (Archive, error) {
+	owners := make(map[string]string)
+	names := make(map[string]bool)
+
+	for _, member := range members {
+		err := CheckMemberName(member.Name)
+		if err != nil {
+			return Archive{}, err
+		}
+
+		if names[member.Name] {
+			return Archive{}, errors.New("Duplicate member " + member.Name)
+		}
+		names[member.Name] = true
+
+		for _, export := range member.Module.Exports {
+			if owner, ok := owners[export.Name]; ok {
+				return Archive{}, errors.New("Duplicate symbol " + export.Name + " in " + owner + " and " + member.Name)
+			}
+
+			owners[export.Name] = member.Name
+		}
+	}
+
+	// sorted so the archive contents do not depend on map order
+	symbols := []string{}
+	for symbol := range owners {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	index := []vputils.NameValue{}
+	for _, symbol := range symbols {
+		index = append(index, vputils.NameValue{symbol, owners[symbol]})
+	}
+
+	return Archive{index, members}, nil
+}
+
+// FindSymbol - find the member that exports a symbol
+func (archive Archive) FindSymbol(symbol string) (int, bool) {
+	for _, nameValue := range archive.Index {
+		if nameValue.Name == symbol {
+			return archive.FindMember(nameValue.Value)
+		}
+	}
+
+	return 0, false
+}
+
+// FindMember - find a member by name
+func (archive Archive) FindMember(name string) (int, bool) {
+	for i, member := range archive.Members {
+		if member.Name == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// Write an archive to a file
+func (archive Archive) Write(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	err = archive.Encode(f)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// Encode - write an archive to a writer
+// the index and member tables are followed by their checksums, as module sections are
+func (archive Archive) Encode(w io.Writer) error {
+	buffer := new(bytes.Buffer)
+
+	err := vputils.WriteString(buffer, "archive")
+	if err != nil {
+		return err
+	}
+
+	err = vputils.WriteString(buffer, FormatVersion)
+	if err != nil {
+		return err
+	}
+
+	members := []vputils.NameValue{}
+	for i, member := range archive.Members {
+		members = append(members, vputils.NameValue{strconv.Itoa(i), member.Name})
+	}
+
+	err = writeTableSection(buffer, "index", archive.Index)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "members", members)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+
+	for _, member := range archive.Members {
+		err = member.Module.Encode(w)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadArchive - read a file into an archive
+func ReadArchive(archiveFile string) (Archive, error) {
+	f, err := os.Open(archiveFile)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	defer f.Close()
+
+	return DecodeArchive(bufio.NewReader(f))
+}
+
+// DecodeArchive - read an archive from a reader
+func DecodeArchive(r io.Reader) (Archive, error) {
+	// keep the bytes read, for the checksums
+	recorder := &recordingReader{r, []byte{}}
+
+	err := readHeader(recorder, "archive")
+	if err != nil {
+		return Archive{}, err
+	}
+
+	err = checkFormatVersion(recorder, "archive")
+	if err != nil {
+		return Archive{}, err
+	}
+
+	index, err := readTableSection(recorder, "index")
+	if err != nil {
+		return Archive{}, err
+	}
+
+	names, err := readTableSection(recorder, "members")
+	if err != nil {
+		return Archive{}, err
+	}
+
+	members := []Member{}
+	for _, nameValue := range names {
+		err = CheckMemberName(nameValue.Value)
+		if err != nil {
+			return Archive{}, err
+		}
+
+		mod, err := Decode(r)
+		if err != nil {
+			return Archive{}, errors.New(err.Error() + " in member " + nameValue.Value)
+		}
+
+		members = append(members, Member{nameValue.Value, mod})
+	}
+
+	archive := Archive{index, members}
+
+	err = archive.checkIndex()
+	if err != nil {
+		return Archive{}, err
+	}
+
+	return archive, nil
+}
+
+// check that each index entry names a member that exports the symbol
+// the linker trusts the index to find the member that defines a symbol
+func (archive Archive) checkIndex() error {
+	for _, nameValue := range archive.Index {
+		i, ok := archive.FindMember(nameValue.Value)
+		if !ok {
+			return errors.New("Index names missing member " + nameValue.Value)
+		}
+
+		exported := false
+		for _, export := range archive.Members[i].Module.Exports {
+			if export.Name == nameValue.Name {
+				exported = true
+			}
+		}
+
+		if !exported {
+			return errors.New("Index names member " + nameValue.Value + " for symbol " + nameValue.Name + " it does not export")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package module
+
+import (
+	"bytes"
+	"github.com/jfitz/virtual-processor/vputils"
+	"testing"
+)
+
+func TestCheckMemberName(t *testing.T) {
+	valids := []string{"print_s.module", "a..b.module"}
+	for _, name := range valids {
+		if err := CheckMemberName(name); err != nil {
+			t.Errorf("CheckMemberName(%q): %s", name, err)
+		}
+	}
+
+	invalids := []string{"", ".", "..", "../print_s.module", "sub/print_s.module", "sub\\print_s.module", "/tmp/print_s.module"}
+	for _, name := range invalids {
+		if err := CheckMemberName(name); err == nil {
+			t.Errorf("CheckMemberName(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestDecodeArchiveRejectsPathMember(t *testing.T) {
+	mod, err := Read("../test/assembler/add_b/ref/program.module")
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	if _, err := MakeArchive([]Member{{"../escape.module", mod}}); err == nil {
+		t.Error("MakeArchive with member ../escape.module succeeded, want error")
+	}
+
+	// built directly, as a hostile archive would be
+	archive := Archive{nil, []Member{{"../escape.module", mod}}}
+
+	var buffer bytes.Buffer
+	err = archive.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	if _, err := DecodeArchive(&buffer); err == nil {
+		t.Error("DecodeArchive with member ../escape.module succeeded, want error")
+	}
+}
+
+func TestDecodeArchiveChecksIndex(t *testing.T) {
+	mod, err := Read("../test/assembler/call/ref/program.module")
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	archive, err := MakeArchive([]Member{{"call.module", mod}})
+	if err != nil {
+		t.Fatalf("MakeArchive: %s", err)
+	}
+
+	var buffer bytes.Buffer
+	err = archive.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	if _, err := DecodeArchive(&buffer); err != nil {
+		t.Fatalf("DecodeArchive: %s", err)
+	}
+
+	// built directly, as a corrupt or hostile archive would be
+	indexes := [][]vputils.NameValue{
+		{{"MAIN", "other.module"}},
+		{{"missing", "call.module"}},
+	}
+
+	for _, index := range indexes {
+		buffer.Reset()
+
+		err = Archive{index, archive.Members}.Encode(&buffer)
+		if err != nil {
+			t.Fatalf("Encode: %s", err)
+		}
+
+		if _, err := DecodeArchive(&buffer); err == nil {
+			t.Errorf("DecodeArchive with index %v succeeded, want error", index)
+		}
+	}
+}
+
+func FuzzDecodeArchive(f *testing.F) {
+	addSeeds(f, "../test/*/*/ref/*.lib")
+
+	f.Fuzz(func(t *testing.T, contents []byte) {
+		archive, err := DecodeArchive(bytes.NewReader(contents))
+		if err != nil {
+			return
+		}
+
+		var buffer bytes.Buffer
+		err = archive.Encode(&buffer)
+		if err != nil {
+			t.Fatalf("Encode of decoded archive: %s", err)
+		}
+
+		_, err = DecodeArchive(&buffer)
+		if err != nil {
+			t.Fatalf("Decode of re-encoded archive: %s", err)
+		}
+	})
+}
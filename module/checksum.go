@@ -0,0 +1,166 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/jfitz/virtual-processor/vputils"
+	"hash/crc32"
+	"io"
+)
+
+// a reader that keeps the bytes it has read
+type recordingReader struct {
+	reader io.Reader
+	bytes  []byte
+}
+
+func (recorder *recordingReader) Read(p []byte) (int, error) {
+	n, err := recorder.reader.Read(p)
+	recorder.bytes = append(recorder.bytes, p[:n]...)
+
+	return n, err
+}
+
+// CRC-32 of some bytes, as text
+func checksum(bytes []byte) string {
+	return fmt.Sprintf("%08X", crc32.ChecksumIEEE(bytes))
+}
+
+// write a checksum for the section that runs from start to the end of the buffer
+func writeChecksum(buffer *bytes.Buffer, start int) error {
+	return vputils.WriteString(buffer, checksum(buffer.Bytes()[start:]))
+}
+
+// write a text table section and its checksum
+func writeTableSection(buffer *bytes.Buffer, name string, table []vputils.NameValue) error {
+	start := buffer.Len()
+
+	err := vputils.WriteTextTable(name, table, buffer)
+	if err != nil {
+		return err
+	}
+
+	return writeChecksum(buffer, start)
+}
+
+// write a binary block section and its checksum
+func writeBlockSection(buffer *bytes.Buffer, name string, block []byte, width int) error {
+	start := buffer.Len()
+
+	err := vputils.WriteBinaryBlock(name, block, buffer, width)
+	if err != nil {
+		return err
+	}
+
+	return writeChecksum(buffer, start)
+}
+
+// write a size block section and its checksum
+func writeSizeSection(buffer *bytes.Buffer, name string, size int, width int) error {
+	start := buffer.Len()
+
+	err := vputils.WriteSizeBlock(name, size, buffer, width)
+	if err != nil {
+		return err
+	}
+
+	return writeChecksum(buffer, start)
+}
+
+// check the checksum that follows a section, which started at start
+// a section that cannot be read is named, so a corrupt length field is traced to its section
+func verifySection(recorder *recordingReader, name string, start int, err error) error {
+	if err != nil {
+		return errors.New(err.Error() + " in section " + name)
+	}
+
+	actual := checksum(recorder.bytes[start:])
+
+	expected, err := vputils.ReadString(recorder)
+	if err != nil {
+		return errors.New(err.Error() + " in checksum of section " + name)
+	}
+
+	if expected != actual {
+		return errors.New("Checksum mismatch in section " + name)
+	}
+
+	return nil
+}
+
+// read a text table section and verify it
+func readTableSection(recorder *recordingReader, name string) ([]vputils.NameValue, error) {
+	start := len(recorder.bytes)
+
+	err := readHeader(recorder, name)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := vputils.ReadTextTable(recorder)
+
+	err = verifySection(recorder, name, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+// read a binary block section and verify it
+func readBlockSection(recorder *recordingReader, name string, width int) ([]byte, error) {
+	start := len(recorder.bytes)
+
+	err := readHeader(recorder, name)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := vputils.ReadBinaryBlock(recorder, width)
+
+	err = verifySection(recorder, name, start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+// read a size block section and verify it
+func readSizeSection(recorder *recordingReader, name string, width int) (int, error) {
+	start := len(recorder.bytes)
+
+	err := readHeader(recorder, name)
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := vputils.ReadSizeBlock(recorder, width)
+
+	err = verifySection(recorder, name, start, err)
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// check the checksum of the whole module
+func verifyFileChecksum(recorder *recordingReader) error {
+	actual := checksum(recorder.bytes)
+
+	expected, err := vputils.ReadString(recorder)
+	if err != nil {
+		return errors.New(err.Error() + " in module checksum")
+	}
+
+	if expected != actual {
+		return errors.New("Checksum mismatch in module file")
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package module
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func encodeModule(t *testing.T, filename string) []byte {
+	mod, err := Read(filename)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	var buffer bytes.Buffer
+	err = mod.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	encoded := encodeModule(t, "../test/assembler/bss/ref/program.module")
+
+	mod, err := Decode(bytes.NewReader(encoded))
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+
+	var buffer bytes.Buffer
+	err = mod.Encode(&buffer)
+	if err != nil {
+		t.Fatalf("Encode: %s", err)
+	}
+
+	if !bytes.Equal(buffer.Bytes(), encoded) {
+		t.Error("re-encoded module differs from the original")
+	}
+}
+
+func TestDecodeNamesCorruptSection(t *testing.T) {
+	encoded := encodeModule(t, "../test/assembler/bss/ref/program.module")
+
+	sections := []string{"code", "data", "const"}
+
+	for _, name := range sections {
+		header := bytes.Index(encoded, []byte(name+"\x00"))
+		if header < 0 {
+			t.Fatalf("no %s section", name)
+		}
+
+		// the block length follows the header
+		lengthField := header + len(name) + 1
+
+		corrupt := make([]byte, len(encoded))
+		copy(corrupt, encoded)
+		corrupt[lengthField] += 2
+
+		_, err := Decode(bytes.NewReader(corrupt))
+		if err == nil {
+			t.Errorf("Decode with corrupt %s length succeeded, want error", name)
+			continue
+		}
+
+		if !strings.HasSuffix(err.Error(), "section "+name) {
+			t.Errorf("corrupt %s length gave %q, want the section named", name, err.Error())
+		}
+	}
+}
+
+func TestDecodeRejectsOldFormatVersion(t *testing.T) {
+	encoded := encodeModule(t, "../test/assembler/add_b/ref/program.module")
+
+	old := bytes.Replace(encoded, []byte("module\x00"+FormatVersion+"\x00"), []byte("module\x001\x00"), 1)
+
+	_, err := Decode(bytes.NewReader(old))
+	if err == nil || !strings.Contains(err.Error(), "format version 1") {
+		t.Errorf("Decode of version 1 module gave %v, want a version error", err)
+	}
+}
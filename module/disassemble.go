@@ -0,0 +1,193 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jfitz/virtual-processor/vputils"
+	"strconv"
+)
+
+// DecodedInstruction - an instruction decoded from code, without executing it
+type DecodedInstruction struct {
+	Address      int
+	Conditionals Conditionals
+	Opcode       byte
+	Definition   MnemonicTargetWidthAddressMode
+	Operand      []byte
+}
+
+// Size - number of bytes in the instruction
+func (inst DecodedInstruction) Size() int {
+	return len(inst.Conditionals) + 1 + len(inst.Operand)
+}
+
+// Bytes - all bytes of the instruction
+func (inst DecodedInstruction) Bytes() []byte {
+	bytes := []byte{}
+	bytes = append(bytes, inst.Conditionals...)
+	bytes = append(bytes, inst.Opcode)
+	bytes = append(bytes, inst.Operand...)
+
+	return bytes
+}
+
+// ToByteString - convert bytes to printable
+func (inst DecodedInstruction) ToByteString() string {
+	s := ""
+
+	for i, b := range inst.Bytes() {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("%02X", b)
+	}
+
+	return s
+}
+
+// OperandValue - the operand as a number, least significant byte first
+func (inst DecodedInstruction) OperandValue() int {
+	value := 0
+
+	for i := len(inst.Operand); i > 0; i-- {
+		value = value*256 + int(inst.Operand[i-1])
+	}
+
+	return value
+}
+
+// IsJump - does the operand hold a code address
+func (inst DecodedInstruction) IsJump() bool {
+	return inst.Definition.Operand == "CODE"
+}
+
+// IsFarCall - does the operand hold an import index
+func (inst DecodedInstruction) IsFarCall() bool {
+	return inst.Definition.Operand == "IMPORT"
+}
+
+// OperandText - the operand in trace notation
+func (inst DecodedInstruction) OperandText(mod Module) string {
+	if len(inst.Operand) == 0 {
+		return ""
+	}
+
+	value := inst.OperandValue()
+	spec := vputils.AddressFormat(len(inst.Operand))
+
+	if inst.IsJump() {
+		return fmt.Sprintf(">"+spec, value)
+	}
+
+	if inst.IsFarCall() {
+		if value < len(mod.Imports) {
+			return mod.Imports[value].Name + "." + mod.Imports[value].Value
+		}
+
+		return fmt.Sprintf("#%02X", value)
+	}
+
+	switch inst.Definition.AddressMode {
+	case "D":
+		return fmt.Sprintf("@"+spec, value)
+	case "I":
+		return fmt.Sprintf("@@"+spec, value)
+	}
+
+	return fmt.Sprintf("="+spec, value)
+}
+
+// ToString - the instruction as text
+func (inst DecodedInstruction) ToString(mod Module) string {
+	s := ""
+
+	if len(inst.Conditionals) > 0 {
+		s += inst.Conditionals.ToString() + " "
+	}
+
+	s += inst.Definition.ToString()
+
+	operand := inst.OperandText(mod)
+	if len(operand) > 0 {
+		s += " " + operand
+	}
+
+	return s
+}
+
+// number of operand bytes that follow an opcode
+func operandSize(def MnemonicTargetWidthAddressMode, mod Module) int {
+	if def.OperandSize != AddressOperand {
+		return def.OperandSize
+	}
+
+	if def.Operand == "CODE" {
+		return mod.CodeAddressWidth
+	}
+
+	return mod.DataAddressWidth
+}
+
+// PrintExports - print the exports table, with addresses in code address format
+func PrintExports(exports []vputils.NameValue, codeAddressWidth int) {
+	tabs := "\t\t\t"
+	fmt.Println(tabs + "EXPORTS")
+
+	codeFormat := vputils.AddressFormat(codeAddressWidth)
+
+	for _, export := range exports {
+		address, _ := strconv.Atoi(export.Value)
+		fmt.Printf(codeFormat+"\t%s\n", address, export.Name)
+	}
+
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+}
+
+// Disassemble - decode the code of a module, in address order
+func Disassemble(mod Module) ([]DecodedInstruction, error) {
+	opcodeDefinitions := DefineOpcodes()
+	code := mod.CodePage.Contents
+
+	instructions := []DecodedInstruction{}
+
+	address := 0
+	for address < len(code) {
+		start := address
+
+		conditionals := Conditionals{}
+		for address < len(code) && code[address] >= 0xE0 && code[address] <= 0xEF {
+			conditionals = append(conditionals, code[address])
+			address++
+		}
+
+		if address == len(code) {
+			return instructions, fmt.Errorf("Conditionals without opcode at %02X", start)
+		}
+
+		opcode := code[address]
+		address++
+
+		def, ok := opcodeDefinitions[opcode]
+		if !ok {
+			return instructions, fmt.Errorf("Invalid opcode %02X at %02X", opcode, start)
+		}
+
+		size := operandSize(def, mod)
+		if address+size > len(code) {
+			return instructions, errors.New("Truncated instruction at end of code")
+		}
+
+		operand := []byte{}
+		operand = append(operand, code[address:address+size]...)
+		address += size
+
+		instruction := DecodedInstruction{start, conditionals, opcode, def, operand}
+		instructions = append(instructions, instruction)
+	}
+
+	return instructions, nil
+}
@@ -0,0 +1,154 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"fmt"
+)
+
+// UnusedOpcode - marks an address mode that has no opcode
+const UnusedOpcode = byte(0x0F)
+
+// VariableStack - a stack effect that depends on the values
+const VariableStack = -1
+
+// AddressOperand - an operand size that is the module's code or data address width
+const AddressOperand = -1
+
+// InstructionSpec - description of one opcode
+// AddressMode is V (value), D (direct), I (indirect), S (stack), or none
+// Operand is the kind of field after the opcode: VALUE, DATA, CODE, IMPORT, or none
+// OperandSize is the bytes in that field, or AddressOperand
+// Pops and Pushes are the bytes taken from and put on the value stack
+type InstructionSpec struct {
+	Opcode      byte
+	Name        string
+	Width       string
+	AddressMode string
+	Operand     string
+	OperandSize int
+	Pops        int
+	Pushes      int
+}
+
+// InstructionSet - every opcode of the processor
+// the assembler, the decoder, and the disassembler are built from this table
+var InstructionSet = []InstructionSpec{
+	{0x00, "NOP", "", "", "", 0, 0, 0},
+	{0x04, "EXIT", "", "", "", 0, 0, 0},
+	{0x05, "KCALL", "", "", "", 0, VariableStack, VariableStack},
+	{0x06, "EXIT", "BYTE", "V", "VALUE", 1, 0, 0},
+	{0x07, "EXIT", "BYTE", "S", "", 0, 1, 0},
+	{0x08, "OUT", "", "S", "", 0, 1, 0},
+
+	{0x60, "PUSH", "BYTE", "V", "VALUE", 1, 0, 1},
+	{0x61, "PUSH", "BYTE", "D", "DATA", AddressOperand, 0, 1},
+	{0x62, "PUSH", "BYTE", "I", "DATA", AddressOperand, 0, 1},
+
+	{0x64, "PUSH", "I16", "V", "VALUE", 2, 0, 2},
+	{0x65, "PUSH", "I16", "D", "DATA", AddressOperand, 0, 2},
+	{0x66, "PUSH", "I16", "I", "DATA", AddressOperand, 0, 2},
+
+	{0x79, "PUSH", "STRING", "D", "DATA", AddressOperand, 0, VariableStack},
+
+	{0x81, "POP", "BYTE", "D", "DATA", AddressOperand, 1, 0},
+	{0x82, "POP", "BYTE", "I", "DATA", AddressOperand, 1, 0},
+	{0x83, "POP", "BYTE", "S", "", 0, 1, 0},
+
+	{0x11, "FLAGS", "BYTE", "D", "DATA", AddressOperand, 0, 0},
+	{0x12, "FLAGS", "BYTE", "I", "DATA", AddressOperand, 0, 0},
+	{0x13, "FLAGS", "BYTE", "S", "", 0, 0, 0},
+
+	{0x21, "INC", "BYTE", "D", "DATA", AddressOperand, 0, 0},
+	{0x22, "INC", "BYTE", "I", "DATA", AddressOperand, 0, 0},
+	{0x31, "DEC", "BYTE", "D", "DATA", AddressOperand, 0, 0},
+	{0x32, "DEC", "BYTE", "I", "DATA", AddressOperand, 0, 0},
+
+	{0xD0, "JUMP", "", "", "CODE", AddressOperand, 0, 0},
+	{0xD1, "CALL", "", "", "CODE", AddressOperand, 0, 0},
+	{0xD2, "RET", "", "", "", 0, 0, 0},
+	{0xD3, "CALL", "", "", "IMPORT", 1, 0, 0},
+
+	{0xA0, "ADD", "BYTE", "S", "", 0, 2, 1},
+	{0xA1, "SUB", "BYTE", "S", "", 0, 2, 1},
+	{0xA2, "MUL", "BYTE", "S", "", 0, 2, 1},
+	{0xA3, "DIV", "BYTE", "S", "", 0, 2, 1},
+
+	{0xC0, "AND", "BYTE", "S", "", 0, 2, 1},
+	{0xC1, "OR", "BYTE", "S", "", 0, 2, 1},
+	{0xC3, "CMP", "BYTE", "S", "", 0, 2, 0},
+}
+
+// the decoder's definition of an opcode
+func (spec InstructionSpec) definition() MnemonicTargetWidthAddressMode {
+	return MnemonicTargetWidthAddressMode{spec.Name, spec.Width, spec.AddressMode, spec.Operand, spec.OperandSize}
+}
+
+// check that the operand size agrees with the operand kind, and the stack effects are possible
+func (spec InstructionSpec) check() error {
+	size := 0
+
+	switch spec.Operand {
+	case "VALUE":
+		size = spec.definition().TargetSize()
+	case "DATA", "CODE":
+		size = AddressOperand
+	case "IMPORT":
+		size = 1
+	case "":
+		size = 0
+	default:
+		return fmt.Errorf("Invalid operand %s for opcode %02X", spec.Operand, spec.Opcode)
+	}
+
+	if spec.OperandSize != size {
+		return fmt.Errorf("Operand size %d for opcode %02X should be %d", spec.OperandSize, spec.Opcode, size)
+	}
+
+	if spec.Pops < VariableStack || spec.Pushes < VariableStack {
+		return fmt.Errorf("Invalid stack effect for opcode %02X", spec.Opcode)
+	}
+
+	return nil
+}
+
+// position of each address mode in the assembler's opcode lists
+var addressModeSlots = map[string]int{"V": 0, "D": 1, "I": 2, "S": 3}
+
+// Mnemonics - the names of the opcodes, in table order
+func Mnemonics() []string {
+	names := []string{}
+	seen := make(map[string]bool)
+
+	for _, spec := range InstructionSet {
+		if !seen[spec.Name] {
+			names = append(names, spec.Name)
+			seen[spec.Name] = true
+		}
+	}
+
+	return names
+}
+
+// FindOpcode - find the opcode for a mnemonic and operand kind
+func FindOpcode(name string, operand string) (byte, bool) {
+	for _, spec := range InstructionSet {
+		if spec.Name == name && spec.Operand == operand {
+			return spec.Opcode, true
+		}
+	}
+
+	return UnusedOpcode, false
+}
+
+// FindInstruction - find the description of an opcode
+func FindInstruction(opcode byte) (InstructionSpec, bool) {
+	for _, spec := range InstructionSet {
+		if spec.Opcode == opcode {
+			return spec, true
+		}
+	}
+
+	return InstructionSpec{}, false
+}
@@ -0,0 +1,145 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"errors"
+	"github.com/jfitz/virtual-processor/vputils"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Binding - the module and code address an import resolves to
+type Binding struct {
+	Module  int
+	Address vputils.Address
+}
+
+// LoadedModule - a module with its pages and bindings, ready to execute
+type LoadedModule struct {
+	Name         string
+	Module       Module
+	CodePage     Page
+	DataPage     Page
+	Bindings     []Binding
+	Instructions []PredecodedInstruction
+}
+
+// ModuleName - the name of a module, from its file name
+func ModuleName(moduleFile string) string {
+	name := filepath.Base(moduleFile)
+
+	return strings.TrimSuffix(name, ".module")
+}
+
+// find a library module file in the search path
+func findLibrary(name string, searchPath []string) (string, error) {
+	for _, directory := range searchPath {
+		libraryFile := filepath.Join(directory, name+".module")
+
+		_, err := os.Stat(libraryFile)
+		if err == nil {
+			return libraryFile, nil
+		}
+	}
+
+	return "", errors.New("Library module " + name + " not found")
+}
+
+// find an exported symbol and make its code address
+func findExport(mod Module, symbol string) (vputils.Address, error) {
+	for _, nameValue := range mod.Exports {
+		if nameValue.Name == symbol {
+			value, err := strconv.Atoi(nameValue.Value)
+			if err != nil {
+				return vputils.Address{}, errors.New("Invalid address for symbol " + symbol)
+			}
+
+			return vputils.MakeAddress(value, mod.CodeAddressWidth, len(mod.CodePage.Contents))
+		}
+	}
+
+	return vputils.Address{}, errors.New("Symbol " + symbol + " not exported")
+}
+
+func makeLoadedModule(name string, mod Module) (LoadedModule, error) {
+	err := mod.CheckInstructionSet()
+	if err != nil {
+		return LoadedModule{}, errors.New(err.Error() + " in " + name)
+	}
+
+	if len(mod.Externals) > 0 {
+		return LoadedModule{}, errors.New("Unresolved external symbol " + mod.Externals[0].Name + " in " + name)
+	}
+
+	loaded := LoadedModule{
+		Name:         name,
+		Module:       mod,
+		CodePage:     mod.CodePage,
+		DataPage:     mod.DataSpace(),
+		Bindings:     []Binding{},
+		Instructions: []PredecodedInstruction{},
+	}
+
+	return loaded, nil
+}
+
+// Load - read the library modules a module imports, and bind imports
+// the first loaded module is the one given; libraries follow in load order
+func Load(name string, mod Module, searchPath []string) ([]LoadedModule, error) {
+	first, err := makeLoadedModule(name, mod)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := []LoadedModule{first}
+	indexes := map[string]int{first.Name: 0}
+
+	// libraries are appended as they are found, so this visits them too
+	for i := 0; i < len(loaded); i++ {
+		for _, nameValue := range loaded[i].Module.Imports {
+			library := nameValue.Name
+			symbol := nameValue.Value
+
+			index, ok := indexes[library]
+			if !ok {
+				libraryFile, err := findLibrary(library, searchPath)
+				if err != nil {
+					return nil, err
+				}
+
+				libraryModule, err := Read(libraryFile)
+				if err != nil {
+					return nil, errors.New(err.Error() + " in " + libraryFile)
+				}
+
+				next, err := makeLoadedModule(library, libraryModule)
+				if err != nil {
+					return nil, err
+				}
+
+				index = len(loaded)
+				loaded = append(loaded, next)
+				indexes[library] = index
+			}
+
+			address, err := findExport(loaded[index].Module, symbol)
+			if err != nil {
+				return nil, errors.New(err.Error() + " by " + library)
+			}
+
+			binding := Binding{index, address}
+			loaded[i].Bindings = append(loaded[i].Bindings, binding)
+		}
+	}
+
+	// decode once, now that imports are bound
+	for i := range loaded {
+		loaded[i].Instructions = loaded[i].Predecode()
+	}
+
+	return loaded, nil
+}
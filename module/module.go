@@ -4,17 +4,33 @@ Package module for virtual-processor
 package module
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"github.com/jfitz/virtual-processor/vputils"
+	"io"
 	"os"
+	"strconv"
 )
 
+// FormatVersion - version of the module file format
+const FormatVersion = "2"
+
+// InstructionSetVersion - version of the instruction set the processor executes
+// version 2 added EXIT with a status
+const InstructionSetVersion = "2"
+
 // Module ------------------------
 type Module struct {
 	Properties       []vputils.NameValue
 	CodePage         Page
 	Exports          []vputils.NameValue
+	Externals        []vputils.NameValue
+	Imports          []vputils.NameValue
+	Relocations      []vputils.NameValue
 	DataPage         Page
+	BSSSize          int
+	ConstPage        Page
 	CodeAddressWidth int
 	DataAddressWidth int
 }
@@ -23,6 +39,67 @@ type Module struct {
 func (mod *Module) Init() {
 }
 
+// DataSpace - build the data address space
+// writable data, then zero-filled reserved space, then constants
+func (mod Module) DataSpace() Page {
+	contents := vputils.Vector{}
+	contents = append(contents, mod.DataPage.Contents...)
+	contents = append(contents, make(vputils.Vector, mod.BSSSize)...)
+	contents = append(contents, mod.ConstPage.Contents...)
+
+	readOnlySize := len(mod.ConstPage.Contents)
+
+	return Page{mod.DataPage.Properties, contents, mod.DataAddressWidth, readOnlySize}
+}
+
+// CheckInstructionSet - check that the processor can execute the module
+func (mod Module) CheckInstructionSet() error {
+	for _, nameValue := range mod.CodePage.Properties {
+		if nameValue.Name == "INSTRUCTION SET VERSION" {
+			if nameValue.Value != InstructionSetVersion {
+				return errors.New("Module requires instruction set version " + nameValue.Value + ", processor supports version " + InstructionSetVersion)
+			}
+
+			return nil
+		}
+	}
+
+	return errors.New("Module does not specify an instruction set version")
+}
+
+// check the format version that follows a file header
+func checkFormatVersion(r io.Reader, kind string) error {
+	version, err := vputils.ReadString(r)
+	if err != nil {
+		return err
+	}
+
+	// files written before the version was added go straight to the properties
+	if version == "properties" || version == "index" {
+		return errors.New("No format version in " + kind + " file, rebuild it with current tools")
+	}
+
+	if version != FormatVersion {
+		return errors.New("Unsupported " + kind + " format version " + version + ", expected version " + FormatVersion)
+	}
+
+	return nil
+}
+
+// read a section header and check its name
+func readHeader(r io.Reader, name string) error {
+	header, err := vputils.ReadString(r)
+	if err != nil {
+		return errors.New(err.Error() + " before " + name + " header")
+	}
+
+	if header != name {
+		return errors.New("Did not find " + name + " header")
+	}
+
+	return nil
+}
+
 // Write a module to a file
 func (mod Module) Write(filename string) error {
 	f, err := os.Create(filename)
@@ -32,18 +109,120 @@ func (mod Module) Write(filename string) error {
 
 	defer f.Close()
 
-	vputils.WriteString(f, "module")
+	err = mod.Encode(f)
+	if err != nil {
+		return err
+	}
 
-	vputils.WriteTextTable("properties", mod.Properties, f)
-	vputils.WriteTextTable("exports", mod.Exports, f)
-	vputils.WriteTextTable("code_properties", mod.CodePage.Properties, f)
-	vputils.WriteBinaryBlock("code", mod.CodePage.Contents, f, mod.CodeAddressWidth)
-	vputils.WriteTextTable("data_properties", mod.DataPage.Properties, f)
-	vputils.WriteBinaryBlock("data", mod.DataPage.Contents, f, mod.DataAddressWidth)
+	return f.Sync()
+}
 
-	f.Sync()
+// Encode - write a module to a writer
+// the module is built in memory so the checksums can be computed
+// each section is followed by its checksum, and the module by a checksum of the whole
+func (mod Module) Encode(w io.Writer) error {
+	buffer := new(bytes.Buffer)
 
-	return nil
+	err := vputils.WriteString(buffer, "module")
+	if err != nil {
+		return err
+	}
+
+	err = vputils.WriteString(buffer, FormatVersion)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "properties", mod.Properties)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "exports", mod.Exports)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "externals", mod.Externals)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "imports", mod.Imports)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "relocations", mod.Relocations)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "code_properties", mod.CodePage.Properties)
+	if err != nil {
+		return err
+	}
+
+	err = writeBlockSection(buffer, "code", mod.CodePage.Contents, mod.CodeAddressWidth)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "data_properties", mod.DataPage.Properties)
+	if err != nil {
+		return err
+	}
+
+	err = writeBlockSection(buffer, "data", mod.DataPage.Contents, mod.DataAddressWidth)
+	if err != nil {
+		return err
+	}
+
+	err = writeSizeSection(buffer, "bss", mod.BSSSize, mod.DataAddressWidth)
+	if err != nil {
+		return err
+	}
+
+	err = writeTableSection(buffer, "const_properties", mod.ConstPage.Properties)
+	if err != nil {
+		return err
+	}
+
+	err = writeBlockSection(buffer, "const", mod.ConstPage.Contents, mod.DataAddressWidth)
+	if err != nil {
+		return err
+	}
+
+	err = writeChecksum(buffer, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buffer.Bytes())
+
+	return err
+}
+
+// get an address width from a property table, default to 1
+func addressWidthProperty(properties []vputils.NameValue, name string) (int, error) {
+	width := 1
+
+	for _, nameValue := range properties {
+		if nameValue.Name == name {
+			value, err := strconv.Atoi(nameValue.Value)
+			if err != nil {
+				return 0, errors.New("Invalid " + name + " '" + nameValue.Value + "'")
+			}
+
+			width = value
+		}
+	}
+
+	if width != 1 && width != 2 && width != 4 {
+		return 0, errors.New("Unsupported " + name + " " + strconv.Itoa(width))
+	}
+
+	return width, nil
 }
 
 // Read a file into a module
@@ -55,86 +234,125 @@ func Read(moduleFile string) (Module, error) {
 
 	defer f.Close()
 
-	header := vputils.ReadString(f)
-	if header != "module" {
-		return Module{}, errors.New("Did not find module header")
+	return Decode(bufio.NewReader(f))
+}
+
+// Decode - read a module from a reader
+// reading stops at the end of the module, so modules can follow one another
+func Decode(r io.Reader) (Module, error) {
+	// keep the bytes read, for the checksums
+	recorder := &recordingReader{r, []byte{}}
+
+	err := readHeader(recorder, "module")
+	if err != nil {
+		return Module{}, err
 	}
 
-	header = vputils.ReadString(f)
-	if header != "properties" {
-		return Module{}, errors.New("Did not find properties header")
+	err = checkFormatVersion(recorder, "module")
+	if err != nil {
+		return Module{}, err
 	}
 
-	properties, err := vputils.ReadTextTable(f)
+	properties, err := readTableSection(recorder, "properties")
 	if err != nil {
 		return Module{}, err
 	}
 
-	header = vputils.ReadString(f)
-	if header != "exports" {
-		return Module{}, errors.New("Did not find exports header")
+	exports, err := readTableSection(recorder, "exports")
+	if err != nil {
+		return Module{}, err
 	}
 
-	exports, err := vputils.ReadTextTable(f)
+	externals, err := readTableSection(recorder, "externals")
 	if err != nil {
 		return Module{}, err
 	}
 
-	header = vputils.ReadString(f)
-	if header != "code_properties" {
-		return Module{}, errors.New("Did not find code_properties header")
+	imports, err := readTableSection(recorder, "imports")
+	if err != nil {
+		return Module{}, err
 	}
 
-	codeProperties, err := vputils.ReadTextTable(f)
+	relocations, err := readTableSection(recorder, "relocations")
 	if err != nil {
 		return Module{}, err
 	}
 
-	codeAddressWidth := 1
+	codeProperties, err := readTableSection(recorder, "code_properties")
+	if err != nil {
+		return Module{}, err
+	}
 
-	header = vputils.ReadString(f)
-	if header != "code" {
-		return Module{}, errors.New("Did not find code header")
+	codeAddressWidth, err := addressWidthProperty(codeProperties, "CODE ADDRESS WIDTH")
+	if err != nil {
+		return Module{}, err
 	}
 
-	code, err := vputils.ReadBinaryBlock(f, codeAddressWidth)
+	code, err := readBlockSection(recorder, "code", codeAddressWidth)
 	if err != nil {
 		return Module{}, err
 	}
 
-	codePage := Page{codeProperties, code, codeAddressWidth}
+	codePage := Page{codeProperties, code, codeAddressWidth, 0}
 
-	header = vputils.ReadString(f)
-	if header != "data_properties" {
-		return Module{}, errors.New("Did not find data_properties header")
+	dataProperties, err := readTableSection(recorder, "data_properties")
+	if err != nil {
+		return Module{}, err
 	}
 
-	dataProperties, err := vputils.ReadTextTable(f)
+	dataAddressWidth, err := addressWidthProperty(dataProperties, "DATA ADDRESS WIDTH")
 	if err != nil {
 		return Module{}, err
 	}
 
-	dataAddressWidth := 1
+	data, err := readBlockSection(recorder, "data", dataAddressWidth)
+	if err != nil {
+		return Module{}, err
+	}
+
+	dataPage := Page{dataProperties, data, dataAddressWidth, 0}
 
-	header = vputils.ReadString(f)
-	if header != "data" {
-		return Module{}, errors.New("Did not find data header")
+	bssSize, err := readSizeSection(recorder, "bss", dataAddressWidth)
+	if err != nil {
+		return Module{}, err
 	}
 
-	data, err := vputils.ReadBinaryBlock(f, dataAddressWidth)
+	constProperties, err := readTableSection(recorder, "const_properties")
 	if err != nil {
 		return Module{}, err
 	}
 
-	dataPage := Page{dataProperties, data, dataAddressWidth}
+	consts, err := readBlockSection(recorder, "const", dataAddressWidth)
+	if err != nil {
+		return Module{}, err
+	}
 
-	// TODO: check data page datawidth is the same as code page datawidth
+	constPage := Page{constProperties, consts, dataAddressWidth, len(consts)}
+
+	err = verifyFileChecksum(recorder)
+	if err != nil {
+		return Module{}, err
+	}
+
+	codeDataAddressWidth, err := addressWidthProperty(codeProperties, "DATA ADDRESS WIDTH")
+	if err != nil {
+		return Module{}, err
+	}
+
+	if codeDataAddressWidth != dataAddressWidth {
+		return Module{}, errors.New("Code and data pages disagree on data address width")
+	}
 
 	mod := Module{
 		Properties:       properties,
 		CodePage:         codePage,
 		Exports:          exports,
+		Externals:        externals,
+		Imports:          imports,
+		Relocations:      relocations,
 		DataPage:         dataPage,
+		BSSSize:          bssSize,
+		ConstPage:        constPage,
 		CodeAddressWidth: codeAddressWidth,
 		DataAddressWidth: dataAddressWidth,
 	}
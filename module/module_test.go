@@ -0,0 +1,48 @@
+package module
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// add the reference files that match a pattern to a fuzz corpus
+func addSeeds(f *testing.F, pattern string) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for _, filename := range filenames {
+		contents, err := os.ReadFile(filename)
+		if err != nil {
+			f.Fatal(err)
+		}
+
+		f.Add(contents)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	addSeeds(f, "../test/*/*/ref/*.module")
+
+	f.Fuzz(func(t *testing.T, contents []byte) {
+		mod, err := Decode(bytes.NewReader(contents))
+		if err != nil {
+			return
+		}
+
+		// anything that decodes must survive a round trip
+		var buffer bytes.Buffer
+		err = mod.Encode(&buffer)
+		if err != nil {
+			t.Fatalf("Encode of decoded module: %s", err)
+		}
+
+		_, err = Decode(&buffer)
+		if err != nil {
+			t.Fatalf("Decode of re-encoded module: %s", err)
+		}
+	})
+}
@@ -0,0 +1,348 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jfitz/virtual-processor/vputils"
+)
+
+// OpcodeHandler - execute an opcode whose conditionals allow it
+// returns the value stack, the runner call (zero for none), and the next PC
+type OpcodeHandler func(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error)
+
+// the handlers, indexed by opcode; an opcode without a handler is invalid
+var opcodeHandlers = [256]OpcodeHandler{
+	0x00: executeNop,
+	0x04: executeExit,
+	0x05: runnerCall(0x05),
+	0x06: executeExitValue,
+	0x07: executeExitStack,
+	0x08: runnerCall(0x08),
+
+	0x11: executeFlags,
+	0x12: executeFlags,
+	0x13: executeFlagsStack,
+
+	0x21: executeInc,
+	0x22: executeInc,
+	0x31: executeDec,
+	0x32: executeDec,
+
+	0x60: executePush,
+	0x61: executePush,
+	0x62: executePush,
+	0x64: executePush,
+	0x65: executePush,
+	0x66: executePush,
+	0x79: executePushString,
+
+	0x81: executePop,
+	0x82: executePop,
+	0x83: executePopStack,
+
+	0xA0: byteOperation(func(a byte, b byte) (byte, error) { return a + b, nil }),
+	0xA1: byteOperation(func(a byte, b byte) (byte, error) { return a - b, nil }),
+	// TODO: push 2 bytes
+	0xA2: byteOperation(func(a byte, b byte) (byte, error) { return a * b, nil }),
+	// TODO: push quotient and remainder (2 bytes)
+	0xA3: byteOperation(divide),
+
+	0xC0: byteOperation(func(a byte, b byte) (byte, error) { return a & b, nil }),
+	0xC1: byteOperation(func(a byte, b byte) (byte, error) { return a | b, nil }),
+	0xC3: executeCompare,
+
+	0xD0: executeJump,
+	0xD1: executeCall,
+	0xD2: executeReturn,
+	0xD3: executeFarCall,
+}
+
+// the opcodes added with RegisterOpcode
+var extensionOpcodes = make(map[byte]bool)
+
+// RegisterOpcode - add an extension opcode to the instruction set, with its handler
+// the opcode is then known to the assembler, the decoder, and the processor
+func RegisterOpcode(spec InstructionSpec, handler OpcodeHandler) error {
+	if handler == nil {
+		return fmt.Errorf("No handler for opcode %02X", spec.Opcode)
+	}
+
+	if spec.Opcode == UnusedOpcode || (spec.Opcode >= 0xE0 && spec.Opcode <= 0xEF) {
+		return fmt.Errorf("Opcode %02X is reserved", spec.Opcode)
+	}
+
+	if len(spec.Name) == 0 {
+		return fmt.Errorf("No mnemonic for opcode %02X", spec.Opcode)
+	}
+
+	err := spec.check()
+	if err != nil {
+		return err
+	}
+
+	for _, other := range InstructionSet {
+		if other.Opcode == spec.Opcode {
+			return fmt.Errorf("Opcode %02X is already defined", spec.Opcode)
+		}
+
+		sameMode := other.Width == spec.Width && other.AddressMode == spec.AddressMode && other.Operand == spec.Operand
+		if other.Name == spec.Name && sameMode {
+			return errors.New("Instruction " + spec.Name + " is already defined for this width and address mode")
+		}
+	}
+
+	InstructionSet = append(InstructionSet, spec)
+	opcodeDefinitions[spec.Opcode] = spec.definition()
+	opcodeHandlers[spec.Opcode] = handler
+	extensionOpcodes[spec.Opcode] = true
+
+	return nil
+}
+
+// UnregisterOpcode - remove an extension opcode added with RegisterOpcode
+// the built-in opcodes cannot be removed
+func UnregisterOpcode(opcode byte) error {
+	if !extensionOpcodes[opcode] {
+		return fmt.Errorf("Opcode %02X is not an extension opcode", opcode)
+	}
+
+	specs := []InstructionSpec{}
+	for _, spec := range InstructionSet {
+		if spec.Opcode != opcode {
+			specs = append(specs, spec)
+		}
+	}
+
+	InstructionSet = specs
+	delete(opcodeDefinitions, opcode)
+	opcodeHandlers[opcode] = nil
+	delete(extensionOpcodes, opcode)
+
+	return nil
+}
+
+// the address of the next instruction
+func nextPC(proc *Processor, instruction InstructionDefinition) vputils.Address {
+	return proc.PC().Increment(instruction.Size)
+}
+
+func executeNop(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+func executeExit(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	// the PC stays at the EXIT
+	return vStack, 0x04, proc.PC(), nil
+}
+
+// EXIT.B immediate value: stop with a status
+func executeExitValue(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	proc.ExitStatus = instruction.Bytes[0]
+
+	return vStack, 0x04, proc.PC(), nil
+}
+
+// EXIT.B (implied stack): stop with the status on top of the stack
+func executeExitStack(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	bytes, vStack, err := vStack.PopByte(1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	proc.ExitStatus = bytes[0]
+
+	return vStack, 0x04, proc.PC(), nil
+}
+
+// a call handled by the runner, not by the processor
+func runnerCall(syscall byte) OpcodeHandler {
+	return func(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+		return vStack, syscall, nextPC(proc, instruction), nil
+	}
+}
+
+// FLAGS.B direct or indirect address
+func executeFlags(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	proc.Flags.Zero = instruction.Bytes[0] == 0
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// FLAGS.B (implied stack)
+func executeFlagsStack(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	buffer, err := vStack.TopByte()
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	proc.Flags.Zero = buffer == 0
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// INC.B direct or indirect address
+func executeInc(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	err := data.PutByte(instruction.Address, instruction.Bytes[0]+1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// DEC.B direct or indirect address
+func executeDec(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	err := data.PutByte(instruction.Address, instruction.Bytes[0]-1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// PUSH immediate value, direct address, or indirect address
+func executePush(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	vStack = vStack.PushBytes(instruction.Bytes)
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// PUSH.STR direct address
+func executePushString(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	s := ""
+	address := instruction.Address
+	b := byte(1)
+
+	for b != 0 {
+		var err error
+		b, err = data.Contents.GetByte(address)
+		if err != nil {
+			return vStack, 0, proc.PC(), err
+		}
+
+		s += string(b)
+		address = address.Increment(1)
+	}
+
+	vStack = vStack.PushString(s)
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// POP.B direct or indirect address
+func executePop(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	bytes, vStack, err := vStack.PopByte(1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	err = data.PutByte(instruction.Address, bytes[0])
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// POP.B value (to nowhere)
+func executePopStack(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	_, vStack, err := vStack.PopByte(1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// pop two bytes, push the result of an operation on them
+// the first operand is the byte that was on top
+func byteOperation(operation func(byte, byte) (byte, error)) OpcodeHandler {
+	return func(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+		bytes1, vStack, err := vStack.PopByte(1)
+		if err != nil {
+			return vStack, 0, proc.PC(), err
+		}
+
+		bytes2, vStack, err := vStack.PopByte(1)
+		if err != nil {
+			return vStack, 0, proc.PC(), err
+		}
+
+		result, err := operation(bytes1[0], bytes2[0])
+		if err != nil {
+			return vStack, 0, proc.PC(), err
+		}
+
+		vStack = vStack.PushByte(result)
+
+		return vStack, 0, nextPC(proc, instruction), nil
+	}
+}
+
+// DIV.B, which faults instead of dividing by zero
+func divide(a byte, b byte) (byte, error) {
+	if b == 0 {
+		return 0, errors.New("Division by zero")
+	}
+
+	return a / b, nil
+}
+
+// CMP.B
+func executeCompare(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	bytes1, vStack, err := vStack.PopByte(1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	bytes2, vStack, err := vStack.PopByte(1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	proc.Flags.Zero = bytes1[0]-bytes2[0] == 0
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+// JUMP
+func executeJump(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	return vStack, 0, instruction.JumpAddress, nil
+}
+
+// CALL
+func executeCall(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	proc.Push(nextPC(proc, instruction))
+
+	return vStack, 0, instruction.JumpAddress, nil
+}
+
+// CALL far, to an imported symbol in another module
+func executeFarCall(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	index := int(instruction.Bytes[0])
+
+	bindings := proc.CurrentModule().Bindings
+	if index >= len(bindings) {
+		return vStack, 0, proc.PC(), fmt.Errorf("Invalid import index %d", index)
+	}
+
+	binding := bindings[index]
+
+	proc.Push(nextPC(proc, instruction))
+	proc.current = binding.Module
+
+	return vStack, 0, binding.Address, nil
+}
+
+// RET
+func executeReturn(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	newpc, err := proc.TopPop()
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	return vStack, 0, newpc, nil
+}
@@ -0,0 +1,218 @@
+package module
+
+import (
+	"github.com/jfitz/virtual-processor/vputils"
+	"testing"
+)
+
+// TWICE.B (implied stack): double the byte on top of the stack
+func executeTwice(proc *Processor, data *Page, vStack vputils.ByteStack, instruction InstructionDefinition) (vputils.ByteStack, byte, vputils.Address, error) {
+	bytes, vStack, err := vStack.PopByte(1)
+	if err != nil {
+		return vStack, 0, proc.PC(), err
+	}
+
+	vStack = vStack.PushByte(bytes[0] * 2)
+
+	return vStack, 0, nextPC(proc, instruction), nil
+}
+
+func TestRegisterOpcode(t *testing.T) {
+	spec := InstructionSpec{0x09, "TWICE", "BYTE", "S", "", 0, 1, 1}
+
+	err := RegisterOpcode(spec, executeTwice)
+	if err != nil {
+		t.Fatalf("RegisterOpcode: %s", err)
+	}
+
+	// the instruction set is shared by the package, so leave it as it was
+	t.Cleanup(func() {
+		if err := UnregisterOpcode(0x09); err != nil {
+			t.Errorf("UnregisterOpcode: %s", err)
+		}
+	})
+
+	opcode, ok := FindOpcode("TWICE", "")
+	if !ok || opcode != 0x09 {
+		t.Errorf("FindOpcode(TWICE) = %02X %v, want 09", opcode, ok)
+	}
+
+	code := vputils.Vector{
+		0x60, 0x15, // PUSH BYTE =15
+		0x09,       // TWICE BYTE
+		0x81, 0x00, // POP BYTE @00
+		0x04, // EXIT
+	}
+
+	for _, predecode := range []bool{true, false} {
+		proc := Processor{Modules: loadTestModule(t, makeTestModule(code, vputils.Vector{0}), predecode)}
+
+		err = runTestModule(&proc)
+		if err != nil {
+			t.Fatalf("predecode %v: %s", predecode, err)
+		}
+
+		if proc.Modules[0].DataPage.Contents[0] != 0x2A {
+			t.Errorf("predecode %v: result is %02X, want 2A", predecode, proc.Modules[0].DataPage.Contents[0])
+		}
+	}
+
+	invalids := []struct {
+		spec    InstructionSpec
+		handler OpcodeHandler
+	}{
+		{InstructionSpec{0x09, "THRICE", "BYTE", "S", "", 0, 1, 1}, executeTwice},
+		{InstructionSpec{0x0A, "TWICE", "BYTE", "S", "", 0, 1, 1}, executeTwice},
+		{InstructionSpec{0xE4, "TWICE", "BYTE", "D", "DATA", AddressOperand, 0, 0}, executeTwice},
+		{InstructionSpec{UnusedOpcode, "TWICE", "BYTE", "D", "DATA", AddressOperand, 0, 0}, executeTwice},
+		{InstructionSpec{0x0A, "", "BYTE", "S", "", 0, 1, 1}, executeTwice},
+		{InstructionSpec{0x0A, "THRICE", "BYTE", "S", "", 0, 1, 1}, nil},
+		{InstructionSpec{0x0A, "THRICE", "BYTE", "V", "VALUE", 2, 0, 1}, executeTwice},
+		{InstructionSpec{0x0A, "THRICE", "BYTE", "S", "", 0, -2, 1}, executeTwice},
+	}
+
+	for _, invalid := range invalids {
+		err := RegisterOpcode(invalid.spec, invalid.handler)
+		if err == nil {
+			t.Errorf("RegisterOpcode(%v) succeeded, want error", invalid.spec)
+		}
+	}
+}
+
+func TestUnregisterOpcode(t *testing.T) {
+	err := RegisterOpcode(InstructionSpec{0x0A, "THRICE", "BYTE", "S", "", 0, 1, 1}, executeTwice)
+	if err != nil {
+		t.Fatalf("RegisterOpcode: %s", err)
+	}
+
+	err = UnregisterOpcode(0x0A)
+	if err != nil {
+		t.Fatalf("UnregisterOpcode: %s", err)
+	}
+
+	if _, ok := FindInstruction(0x0A); ok {
+		t.Error("opcode 0A is in the instruction set after UnregisterOpcode")
+	}
+
+	if _, ok := opcodeDefinitions[0x0A]; ok || opcodeHandlers[0x0A] != nil {
+		t.Error("opcode 0A is still decoded after UnregisterOpcode")
+	}
+
+	for _, opcode := range []byte{0x0A, 0x60} {
+		if err := UnregisterOpcode(opcode); err == nil {
+			t.Errorf("UnregisterOpcode(%02X) succeeded, want error", opcode)
+		}
+	}
+}
+
+func TestInstructionSetIsConsistent(t *testing.T) {
+	opcodes := make(map[byte]bool)
+
+	for _, spec := range InstructionSet {
+		if err := spec.check(); err != nil {
+			t.Error(err)
+		}
+
+		if opcodes[spec.Opcode] {
+			t.Errorf("Opcode %02X is defined twice", spec.Opcode)
+		}
+		opcodes[spec.Opcode] = true
+	}
+}
+
+// each opcode changes the value stack by what the instruction set says
+func TestStackEffects(t *testing.T) {
+	for _, spec := range InstructionSet {
+		// far calls need a module to call
+		if spec.Pops == VariableStack || spec.Pushes == VariableStack || spec.Operand == "IMPORT" {
+			continue
+		}
+
+		code := vputils.Vector{spec.Opcode}
+		code = append(code, make(vputils.Vector, operandSize(spec.definition(), makeTestModule(nil, nil)))...)
+
+		for _, predecode := range []bool{true, false} {
+			proc := Processor{Modules: loadTestModule(t, makeTestModule(code, vputils.Vector{0, 0, 0, 0}), predecode)}
+
+			start, _ := vputils.MakeAddress(0, 1, len(code))
+			proc.SetPC(start)
+
+			// a return address for RET
+			proc.Push(start)
+
+			before := vputils.ByteStack{1, 1, 1, 1}
+
+			after, syscall, err := proc.ExecuteInstruction(before, false)
+			if err != nil {
+				t.Errorf("opcode %02X, predecode %v: %s", spec.Opcode, predecode, err)
+				continue
+			}
+
+			// the runner does the stack work for its calls
+			if syscall != 0 && syscall != 0x04 {
+				continue
+			}
+
+			if len(after)-len(before) != spec.Pushes-spec.Pops {
+				t.Errorf("opcode %02X, predecode %v, changes the stack by %d bytes, want %d", spec.Opcode, predecode, len(after)-len(before), spec.Pushes-spec.Pops)
+			}
+		}
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	code := vputils.Vector{
+		0x60, 0x00, // PUSH BYTE =00
+		0x60, 0x90, // PUSH BYTE =90
+		0xA3, // DIV BYTE
+		0x04, // EXIT
+	}
+
+	proc := Processor{Modules: loadTestModule(t, makeTestModule(code, vputils.Vector{0}), true)}
+
+	err := runTestModule(&proc)
+	if err == nil || err.Error() != "Division by zero" {
+		t.Errorf("DIV by zero gave %v, want Division by zero", err)
+	}
+}
+
+func TestFarCallWithoutBinding(t *testing.T) {
+	proc := Processor{Modules: loadTestModule(t, makeTestModule(vputils.Vector{0x04}, vputils.Vector{0}), true)}
+	data := &proc.Modules[0].DataPage
+
+	instruction := InstructionDefinition{FullOpcode: []byte{0xD3, 0x05}, Size: 2, Bytes: []byte{0x05}}
+
+	_, _, err := proc.ExecuteOpcode(data, 0xD3, vputils.ByteStack{}, instruction, true)
+	if err == nil {
+		t.Error("far call to import 5 with no bindings succeeded, want error")
+	}
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	proc := Processor{Modules: loadTestModule(b, makeTestModule(vputils.Vector{0x04}, vputils.Vector{0}), true)}
+	data := &proc.Modules[0].DataPage
+
+	nop := InstructionDefinition{FullOpcode: []byte{0x00}, Size: 0}
+	push := InstructionDefinition{FullOpcode: []byte{0x60, 0x01}, Size: 0, Bytes: []byte{0x01}}
+	pop := InstructionDefinition{FullOpcode: []byte{0x83}, Size: 0}
+
+	vStack := vputils.ByteStack{}
+	var err error
+
+	for i := 0; i < b.N; i++ {
+		vStack, _, err = proc.ExecuteOpcode(data, 0x00, vStack, nop, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		vStack, _, err = proc.ExecuteOpcode(data, 0x60, vStack, push, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		vStack, _, err = proc.ExecuteOpcode(data, 0x83, vStack, pop, true)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
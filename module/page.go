@@ -13,6 +13,26 @@ type Page struct {
 	Properties   []vputils.NameValue
 	Contents     vputils.Vector
 	AddressWidth int
+	ReadOnlySize int
+}
+
+// IsReadOnly - is the address in the read-only tail of the page
+// an address outside the page is not, so a write to it reports the range
+func (page Page) IsReadOnly(address vputils.Address) bool {
+	if address.Value < 0 || address.Value >= len(page.Contents) {
+		return false
+	}
+
+	return address.Value >= len(page.Contents)-page.ReadOnlySize
+}
+
+// PutByte - put byte, unless the address is read-only
+func (page Page) PutByte(address vputils.Address, value byte) error {
+	if page.IsReadOnly(address) {
+		return errors.New("Write to read-only address " + address.ToString())
+	}
+
+	return page.Contents.PutByte(address, value)
 }
 
 // GetAddress - get bytes and convert to an address
@@ -0,0 +1,35 @@
+package module
+
+import (
+	"github.com/jfitz/virtual-processor/vputils"
+	"strings"
+	"testing"
+)
+
+func TestPutByteChecksRangeBeforeReadOnly(t *testing.T) {
+	cases := []struct {
+		readOnlySize int
+		address      int
+		message      string
+	}{
+		{0, 1, ""},
+		{0, 4, "out of range"},
+		{2, 1, ""},
+		{2, 2, "read-only"},
+		{2, 4, "out of range"},
+	}
+
+	for _, c := range cases {
+		page := Page{[]vputils.NameValue{}, make(vputils.Vector, 4), 1, c.readOnlySize}
+		address, _ := vputils.MakeAddress(c.address, 1, 255)
+
+		err := page.PutByte(address, 0x2A)
+		if c.message == "" {
+			if err != nil {
+				t.Errorf("PutByte(%d) with %d read-only: %s", c.address, c.readOnlySize, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("PutByte(%d) with %d read-only = %v, want %s", c.address, c.readOnlySize, err, c.message)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"fmt"
+	"github.com/jfitz/virtual-processor/vputils"
+)
+
+// PredecodedInstruction - the parts of an instruction that do not depend on data
+// Target is the data address (direct), the pointer address (indirect),
+// or the code address (jump, call, and far call)
+type PredecodedInstruction struct {
+	Valid        bool
+	Conditionals Conditionals
+	Opcode       byte
+	Definition   MnemonicTargetWidthAddressMode
+	FullOpcode   []byte
+	Size         int
+	Immediate    []byte
+	Target       vputils.Address
+}
+
+// the opcodes, built once from the instruction set
+var opcodeDefinitions = DefineOpcodes()
+
+// decode the operand of an instruction against the pages and bindings
+func predecodeInstruction(instruction DecodedInstruction, loaded LoadedModule) (PredecodedInstruction, error) {
+	def := instruction.Definition
+	target := vputils.Address{}
+	err := error(nil)
+
+	switch def.Operand {
+	case "DATA":
+		target, err = vputils.BytesToAddress(instruction.Operand, len(loaded.DataPage.Contents))
+
+	case "CODE":
+		target, err = vputils.BytesToAddress(instruction.Operand, len(loaded.CodePage.Contents))
+
+	case "IMPORT":
+		index := int(instruction.Operand[0])
+		if index >= len(loaded.Bindings) {
+			err = fmt.Errorf("Invalid import index %d", index)
+		} else {
+			target = loaded.Bindings[index].Address
+		}
+	}
+
+	if err != nil {
+		return PredecodedInstruction{}, err
+	}
+
+	fullOpcode := []byte{instruction.Opcode}
+	fullOpcode = append(fullOpcode, instruction.Operand...)
+
+	predecoded := PredecodedInstruction{
+		Valid:        true,
+		Conditionals: instruction.Conditionals,
+		Opcode:       instruction.Opcode,
+		Definition:   def,
+		FullOpcode:   fullOpcode,
+		Size:         def.OpcodeSize() + len(instruction.Operand),
+		Immediate:    instruction.Operand,
+		Target:       target,
+	}
+
+	return predecoded, nil
+}
+
+// Predecode - decode the code page once, indexed by instruction address
+// instructions that do not decode are left invalid, and are decoded when executed
+func (loaded LoadedModule) Predecode() []PredecodedInstruction {
+	predecoded := make([]PredecodedInstruction, len(loaded.CodePage.Contents))
+
+	// decoding stops at the first bad opcode, the instructions before it are kept
+	instructions, _ := Disassemble(loaded.Module)
+
+	for _, instruction := range instructions {
+		item, err := predecodeInstruction(instruction, loaded)
+		if err == nil {
+			predecoded[instruction.Address] = item
+		}
+	}
+
+	return predecoded
+}
+
+// complete the instruction with the values from the data page
+func (predecoded PredecodedInstruction) complete(data Page, trace bool) (InstructionDefinition, error) {
+	def := predecoded.Definition
+
+	dataAddress1 := vputils.Address{}
+	dataAddress := vputils.Address{}
+	jumpAddress := vputils.Address{}
+	bytes := []byte{}
+	valueStr := ""
+
+	err := error(nil)
+
+	switch def.AddressMode {
+	case "V":
+		// copied, because the stack reverses pushed bytes in place
+		bytes = append(bytes, predecoded.Immediate...)
+
+		if trace {
+			valueStr = bytesToString(bytes)
+		}
+
+	case "D":
+		dataAddress = predecoded.Target
+
+	case "I":
+		dataAddress1 = predecoded.Target
+
+		dataAddress, err = data.GetAddress(dataAddress1, data.AddressWidth, len(data.Contents))
+		if err != nil {
+			return InstructionDefinition{}, err
+		}
+	}
+
+	if def.AddressMode == "D" || def.AddressMode == "I" {
+		buffer, err := data.Contents.GetBytes(dataAddress, def.dataSize())
+		if err != nil {
+			return InstructionDefinition{}, err
+		}
+
+		bytes = append(bytes, buffer...)
+
+		if trace {
+			valueStr = bytesToString(bytes)
+		}
+	}
+
+	switch def.Operand {
+	case "CODE":
+		jumpAddress = predecoded.Target
+
+	case "IMPORT":
+		jumpAddress = predecoded.Target
+		bytes = append(bytes, predecoded.Immediate...)
+	}
+
+	instruction := InstructionDefinition{predecoded.FullOpcode, dataAddress1, dataAddress, predecoded.Size, jumpAddress, bytes, valueStr}
+
+	return instruction, nil
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jfitz/virtual-processor/vputils"
+	"io"
+	"os"
 	"strings"
 )
 
@@ -124,6 +126,8 @@ type MnemonicTargetWidthAddressMode struct {
 	Name        string
 	Width       string
 	AddressMode string
+	Operand     string
+	OperandSize int
 }
 
 // ToString ----------------------
@@ -169,56 +173,41 @@ func (def MnemonicTargetWidthAddressMode) TargetSize() int {
 	return targetSize
 }
 
+// the bytes read from a data address
+// strings are read by the handler, so only their first byte is read here
+func (def MnemonicTargetWidthAddressMode) dataSize() int {
+	size := def.TargetSize()
+	if size == 0 {
+		size = 1
+	}
+
+	return size
+}
+
+// a value in little-endian bytes, as hex
+func bytesToString(bytes []byte) string {
+	s := ""
+
+	for i := len(bytes); i > 0; i-- {
+		s += fmt.Sprintf("%02X", bytes[i-1])
+	}
+
+	return s
+}
+
 // -------------------------------
 // -------------------------------
 
 // ByteToMnemonic ----------------
 type ByteToMnemonic map[byte]MnemonicTargetWidthAddressMode
 
-// DefineOpcodes - define the table of opcodes
+// DefineOpcodes - define the table of opcodes, from the instruction set
 func DefineOpcodes() ByteToMnemonic {
 	bytesToMnemonics := make(ByteToMnemonic)
 
-	bytesToMnemonics[0x00] = MnemonicTargetWidthAddressMode{"NOP", "", ""}
-	bytesToMnemonics[0x04] = MnemonicTargetWidthAddressMode{"EXIT", "", ""}
-	bytesToMnemonics[0x05] = MnemonicTargetWidthAddressMode{"KCALL", "", ""}
-	bytesToMnemonics[0x08] = MnemonicTargetWidthAddressMode{"OUT", "", "S"}
-
-	bytesToMnemonics[0x60] = MnemonicTargetWidthAddressMode{"PUSH", "BYTE", "V"}
-	bytesToMnemonics[0x61] = MnemonicTargetWidthAddressMode{"PUSH", "BYTE", "D"}
-	bytesToMnemonics[0x62] = MnemonicTargetWidthAddressMode{"PUSH", "BYTE", "I"}
-
-	bytesToMnemonics[0x64] = MnemonicTargetWidthAddressMode{"PUSH", "I16", "V"}
-	bytesToMnemonics[0x65] = MnemonicTargetWidthAddressMode{"PUSH", "I16", "D"}
-	bytesToMnemonics[0x66] = MnemonicTargetWidthAddressMode{"PUSH", "I16", "I"}
-
-	bytesToMnemonics[0x79] = MnemonicTargetWidthAddressMode{"PUSH", "STRING", "D"}
-
-	bytesToMnemonics[0x81] = MnemonicTargetWidthAddressMode{"POP", "BYTE", "D"}
-	bytesToMnemonics[0x82] = MnemonicTargetWidthAddressMode{"POP", "BYTE", "I"}
-	bytesToMnemonics[0x83] = MnemonicTargetWidthAddressMode{"POP", "BYTE", "S"}
-
-	bytesToMnemonics[0x11] = MnemonicTargetWidthAddressMode{"FLAGS", "BYTE", "D"}
-	bytesToMnemonics[0x12] = MnemonicTargetWidthAddressMode{"FLAGS", "BYTE", "I"}
-	bytesToMnemonics[0x13] = MnemonicTargetWidthAddressMode{"FLAGS", "BYTE", "S"}
-
-	bytesToMnemonics[0x21] = MnemonicTargetWidthAddressMode{"INC", "BYTE", "D"}
-	bytesToMnemonics[0x22] = MnemonicTargetWidthAddressMode{"INC", "BYTE", "I"}
-	bytesToMnemonics[0x31] = MnemonicTargetWidthAddressMode{"DEC", "BYTE", "D"}
-	bytesToMnemonics[0x32] = MnemonicTargetWidthAddressMode{"DEC", "BYTE", "I"}
-
-	bytesToMnemonics[0xD0] = MnemonicTargetWidthAddressMode{"JUMP", "", ""}
-	bytesToMnemonics[0xD1] = MnemonicTargetWidthAddressMode{"CALL", "", ""}
-	bytesToMnemonics[0xD2] = MnemonicTargetWidthAddressMode{"RET", "", ""}
-
-	bytesToMnemonics[0xA0] = MnemonicTargetWidthAddressMode{"ADD", "BYTE", ""}
-	bytesToMnemonics[0xA1] = MnemonicTargetWidthAddressMode{"SUB", "BYTE", ""}
-	bytesToMnemonics[0xA2] = MnemonicTargetWidthAddressMode{"MUL", "BYTE", ""}
-	bytesToMnemonics[0xA3] = MnemonicTargetWidthAddressMode{"DIV", "BYTE", ""}
-
-	bytesToMnemonics[0xC0] = MnemonicTargetWidthAddressMode{"AND", "BYTE", ""}
-	bytesToMnemonics[0xC1] = MnemonicTargetWidthAddressMode{"OR", "BYTE", ""}
-	bytesToMnemonics[0xC3] = MnemonicTargetWidthAddressMode{"CMP", "BYTE", ""}
+	for _, spec := range InstructionSet {
+		bytesToMnemonics[spec.Opcode] = spec.definition()
+	}
 
 	return bytesToMnemonics
 }
@@ -230,71 +219,36 @@ type OpcodeBytes struct {
 	AddressOpcodes TargetWidthToOpcodes
 }
 
+// MakeMnemonicTargetWidthAddressModes - opcodes for the assembler, from the instruction set
+// address modes without an opcode hold UnusedOpcode
 func MakeMnemonicTargetWidthAddressModes() map[string]OpcodeBytes {
 	opcodeDefs := map[string]OpcodeBytes{}
 
-	emptyOpcodes := make(TargetWidthToOpcodes)
-
-	opcodeDefs["NOP"] = OpcodeBytes{0x00, emptyOpcodes}
-	opcodeDefs["EXIT"] = OpcodeBytes{0x04, emptyOpcodes}
-	opcodeDefs["KCALL"] = OpcodeBytes{0x05, emptyOpcodes}
-	opcodeDefs["OUT"] = OpcodeBytes{0x08, emptyOpcodes}
-
-	opcodeDefs["JUMP"] = OpcodeBytes{0xD0, emptyOpcodes}
-
-	opcodeDefs["CALL"] = OpcodeBytes{0xD1, emptyOpcodes}
-
-	opcodeDefs["RET"] = OpcodeBytes{0xD2, emptyOpcodes}
-
-	pushOpcodes := make(TargetWidthToOpcodes)
-	pushOpcodes["BYTE"] = []byte{0x60, 0x61, 0x62, 0x0F}
-	pushOpcodes["I16"] = []byte{0x64, 0x65, 0x66, 0x0F}
-	pushOpcodes["STRING"] = []byte{0x0F, 0x79, 0x7A, 0x0F}
-	opcodeDefs["PUSH"] = OpcodeBytes{0x0F, pushOpcodes}
-
-	popOpcodes := make(TargetWidthToOpcodes)
-	popOpcodes["BYTE"] = []byte{0x0F, 0x81, 0x82, 0x83}
-	opcodeDefs["POP"] = OpcodeBytes{0x0F, popOpcodes}
-
-	flagsOpcodes := make(TargetWidthToOpcodes)
-	flagsOpcodes["BYTE"] = []byte{0x10, 0x11, 0x12, 0x13}
-	opcodeDefs["FLAGS"] = OpcodeBytes{0x0F, flagsOpcodes}
-
-	incOpcodes := make(TargetWidthToOpcodes)
-	incOpcodes["BYTE"] = []byte{0x0F, 0x21, 0x22, 0x23}
-	opcodeDefs["INC"] = OpcodeBytes{0x0F, incOpcodes}
-
-	decOpcodes := make(TargetWidthToOpcodes)
-	decOpcodes["BYTE"] = []byte{0x0F, 0x31, 0x32, 0x33}
-	opcodeDefs["DEC"] = OpcodeBytes{0x0F, decOpcodes}
-
-	addOpcodes := make(TargetWidthToOpcodes)
-	addOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xA0}
-	opcodeDefs["ADD"] = OpcodeBytes{0x0F, addOpcodes}
-
-	subOpcodes := make(TargetWidthToOpcodes)
-	subOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xA1}
-	opcodeDefs["SUB"] = OpcodeBytes{0x0F, subOpcodes}
-
-	mulOpcodes := make(TargetWidthToOpcodes)
-	mulOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xA2}
-	opcodeDefs["MUL"] = OpcodeBytes{0x0F, mulOpcodes}
+	for _, spec := range InstructionSet {
+		// far calls are selected by the target, not by the mnemonic
+		if spec.Operand == "IMPORT" {
+			continue
+		}
 
-	divOpcodes := make(TargetWidthToOpcodes)
-	divOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xA3}
-	opcodeDefs["DIV"] = OpcodeBytes{0x0F, divOpcodes}
+		opcodeDef, ok := opcodeDefs[spec.Name]
+		if !ok {
+			opcodeDef = OpcodeBytes{UnusedOpcode, make(TargetWidthToOpcodes)}
+		}
 
-	andOpcodes := make(TargetWidthToOpcodes)
-	andOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xC0}
-	opcodeDefs["AND"] = OpcodeBytes{0x0F, andOpcodes}
+		if len(spec.Width) == 0 {
+			opcodeDef.Opcode = spec.Opcode
+		} else {
+			opcodes, ok := opcodeDef.AddressOpcodes[spec.Width]
+			if !ok {
+				opcodes = []byte{UnusedOpcode, UnusedOpcode, UnusedOpcode, UnusedOpcode}
+				opcodeDef.AddressOpcodes[spec.Width] = opcodes
+			}
 
-	orOpcodes := make(TargetWidthToOpcodes)
-	orOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xC1}
-	opcodeDefs["OR"] = OpcodeBytes{0x0F, orOpcodes}
+			opcodes[addressModeSlots[spec.AddressMode]] = spec.Opcode
+		}
 
-	cmpOpcodes := make(TargetWidthToOpcodes)
-	cmpOpcodes["BYTE"] = []byte{0x0F, 0x0F, 0x0F, 0xC3}
-	opcodeDefs["CMP"] = OpcodeBytes{0x0F, cmpOpcodes}
+		opcodeDefs[spec.Name] = opcodeDef
+	}
 
 	return opcodeDefs
 }
@@ -326,9 +280,29 @@ func (def InstructionDefinition) ToByteString() string {
 
 // Processor ---------------------
 type Processor struct {
-	pc       vputils.Address
-	RetStack vputils.AddressStack
-	Flags    FlagsGroup
+	pc          vputils.Address
+	RetStack    vputils.AddressStack
+	Flags       FlagsGroup
+	Modules     []LoadedModule
+	current     int
+	moduleStack []int
+	ExitStatus  byte
+	TraceOutput io.Writer
+}
+
+// CurrentModule - the module holding the PC
+func (proc Processor) CurrentModule() LoadedModule {
+	return proc.Modules[proc.current]
+}
+
+// write a line of trace, to standard error unless there is a trace writer
+func (proc *Processor) traceLine(line string) {
+	if proc.TraceOutput == nil {
+		fmt.Fprintln(os.Stderr, line)
+		return
+	}
+
+	fmt.Fprintln(proc.TraceOutput, line)
 }
 
 // SetPC - set the PC
@@ -352,16 +326,23 @@ func (proc *Processor) IncrementPC(count int) {
 	proc.pc = proc.pc.Increment(count)
 }
 
-// Push - push a value
+// Push - push a value, and remember the current module
 func (proc *Processor) Push(address vputils.Address) {
 	proc.RetStack = proc.RetStack.Push(address)
+	proc.moduleStack = append(proc.moduleStack, proc.current)
 }
 
-// TopPop - pop the top value
+// TopPop - pop the top value, and return to its module
 func (proc *Processor) TopPop() (vputils.Address, error) {
 	address, retStack, err := proc.RetStack.TopPop()
 	proc.RetStack = retStack
 
+	if err == nil {
+		last := len(proc.moduleStack) - 1
+		proc.current = proc.moduleStack[last]
+		proc.moduleStack = proc.moduleStack[:last]
+	}
+
 	return address, err
 }
 
@@ -419,13 +400,13 @@ func (proc Processor) DecodeInstruction(opcode byte, def MnemonicTargetWidthAddr
 		bytes := dataAddress.ToBytes()
 		fullOpcode = append(fullOpcode, bytes...)
 
-		buffer, err := code.DirectByte(proc.PC(), data)
+		buffer, err := data.Contents.GetBytes(dataAddress, def.dataSize())
 		if err != nil {
 			return InstructionDefinition{}, err
 		}
 
-		workBytes = append(workBytes, buffer)
-		valueStr = fmt.Sprintf("%02X", buffer)
+		workBytes = append(workBytes, buffer...)
+		valueStr = bytesToString(buffer)
 
 		instructionSize += dataAddress.Size
 	}
@@ -446,19 +427,19 @@ func (proc Processor) DecodeInstruction(opcode byte, def MnemonicTargetWidthAddr
 			return InstructionDefinition{}, err
 		}
 
-		buffer, err := code.IndirectByte(proc.PC(), data)
+		buffer, err := data.Contents.GetBytes(dataAddress, def.dataSize())
 		if err != nil {
 			return InstructionDefinition{}, err
 		}
 
-		workBytes = append(workBytes, buffer)
-		valueStr = fmt.Sprintf("%02X", buffer)
+		workBytes = append(workBytes, buffer...)
+		valueStr = bytesToString(buffer)
 
 		instructionSize += dataAddress1.Size
 	}
 
 	// decode jump/call target
-	if opcode == 0xD0 || opcode == 0xD1 {
+	if def.Operand == "CODE" {
 		jumpAddress, err = code.JumpAddress(proc.PC())
 		if err != nil {
 			return InstructionDefinition{}, err
@@ -469,410 +450,55 @@ func (proc Processor) DecodeInstruction(opcode byte, def MnemonicTargetWidthAddr
 		instructionSize += jumpAddress.Size
 	}
 
-	instruction := InstructionDefinition{fullOpcode, dataAddress1, dataAddress, instructionSize, jumpAddress, workBytes, valueStr}
-
-	return instruction, nil
-}
-
-// ExecuteOpcode - execute one opcode
-func (proc *Processor) ExecuteOpcode(data *Page, opcode byte, vStack vputils.ByteStack, instruction InstructionDefinition, execute bool) (vputils.ByteStack, byte, error) {
-	dataAddress := instruction.Address
-	instructionSize := instruction.Size
-	jumpAddress := instruction.JumpAddress
-	bytes := instruction.Bytes
-
-	err := errors.New("")
-
-	syscall := byte(0)
-
-	pc := proc.PC()
-	newpc := pc
-
-	bytes1 := []byte{}
-	bytes2 := []byte{}
-
-	// execute opcode
-	switch opcode {
-	case 0x00:
-		// NOP
-		newpc = pc.Increment(instructionSize)
-
-	case 0x04:
-		// EXIT
-		if execute {
-			syscall = opcode
-		}
-
-		// newpc = pc.Increment(instructionSize)
-
-	case 0x05:
-		// KCALL - kernel call
-		if execute {
-			syscall = opcode
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x08:
-		// OUT (implied stack)
-		if execute {
-			syscall = opcode
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x11:
-		// FLAGS.B direct address
-		if execute {
-			proc.Flags.Zero = bytes[0] == 0
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x12:
-		// FLAGS.B indirect address
-		if execute {
-			proc.Flags.Zero = bytes[0] == 0
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x13:
-		// FLAGS.B (implied stack)
-		if execute {
-			buffer, err := vStack.TopByte()
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			proc.Flags.Zero = buffer == 0
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x21:
-		// INC.B direct address
-		if execute {
-			bytes[0]++
-
-			err = data.Contents.PutByte(dataAddress, bytes[0])
-			if err != nil {
-				return vStack, syscall, err
-			}
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x22:
-		// INC.B indirect address
-		if execute {
-			bytes[0]++
-
-			err = data.Contents.PutByte(dataAddress, bytes[0])
-			if err != nil {
-				return vStack, syscall, err
-			}
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x31:
-		// DEC.B direct address
-		if execute {
-			bytes[0]--
-
-			err = data.Contents.PutByte(dataAddress, bytes[0])
-			if err != nil {
-				return vStack, syscall, err
-			}
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x32:
-		// DEC.B indirect address
-		if execute {
-			bytes[0]--
-
-			err = data.Contents.PutByte(dataAddress, bytes[0])
-			if err != nil {
-				return vStack, syscall, err
-			}
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x60:
-		// PUSH.B immediate value
-		if execute {
-			vStack = vStack.PushBytes(bytes)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x61:
-		// PUSH.B direct address
-		if execute {
-			vStack = vStack.PushBytes(bytes)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x62:
-		// PUSH.B indirect address
-		if execute {
-			vStack = vStack.PushBytes(bytes)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x64:
-		// PUSH.I16 immediate value
-		if execute {
-			vStack = vStack.PushBytes(bytes)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x65:
-		// PUSH.I16 direct address
-		if execute {
-			vStack = vStack.PushBytes(bytes)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x66:
-		// PUSH.I16 indirect address
-		if execute {
-			vStack = vStack.PushBytes(bytes)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x79:
-		// PUSH.STR direct address
-		if execute {
-			s := ""
-			address := dataAddress
-			b := byte(1)
-
-			for b != 0 {
-				b, err = data.Contents.GetByte(address)
-				if err != nil {
-					return vStack, syscall, err
-				}
-
-				c := string(b)
-				s += c
-				address = address.Increment(1)
-			}
-
-			vStack = vStack.PushString(s)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x81:
-		// POP.B direct address
-		if execute {
-			bytes, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			err = data.Contents.PutByte(dataAddress, bytes[0])
-			if err != nil {
-				return vStack, syscall, err
-			}
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0x83:
-		// POP.B value (to nowhere)
-		if execute {
-			bytes, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0xA0:
-		// ADD.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] + bytes2[0]
-			vStack = vStack.PushByte(value)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0xA1:
-		// SUB.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] - bytes2[0]
-			vStack = vStack.PushByte(value)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0xA2:
-		// MUL.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] * bytes2[0]
-			// TODO: push 2 bytes
-			vStack = vStack.PushByte(value)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0xA3:
-		// DIV.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] / bytes2[0]
-			// TODO: push quotient and remainder (2 bytes)
-			vStack = vStack.PushByte(value)
+	// decode far call import index, target is in another module
+	if def.Operand == "IMPORT" {
+		workBytes, err = code.ImmediateByte(proc.PC())
+		if err != nil {
+			return InstructionDefinition{}, err
 		}
 
-		newpc = pc.Increment(instructionSize)
-
-	case 0xC0:
-		// AND.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] & bytes2[0]
-			vStack = vStack.PushByte(value)
+		bindings := proc.CurrentModule().Bindings
+		index := int(workBytes[0])
+		if index >= len(bindings) {
+			return InstructionDefinition{}, fmt.Errorf("Invalid import index %d", index)
 		}
 
-		newpc = pc.Increment(instructionSize)
+		jumpAddress = bindings[index].Address
 
-	case 0xC1:
-		// OR.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] | bytes2[0]
-			vStack = vStack.PushByte(value)
-		}
-
-		newpc = pc.Increment(instructionSize)
-
-	case 0xC3:
-		// CMP.B
-		if execute {
-			bytes1, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			bytes2, vStack, err = vStack.PopByte(1)
-			if err != nil {
-				return vStack, syscall, err
-			}
-
-			value := bytes1[0] - bytes2[0]
-
-			proc.Flags.Zero = value == 0
-		}
-
-		newpc = pc.Increment(instructionSize)
+		fullOpcode = append(fullOpcode, workBytes...)
+		instructionSize += 1
+	}
 
-	case 0xD0:
-		// JUMP
-		if execute {
-			newpc = jumpAddress
-		} else {
-			newpc = pc.Increment(instructionSize)
-		}
+	instruction := InstructionDefinition{fullOpcode, dataAddress1, dataAddress, instructionSize, jumpAddress, workBytes, valueStr}
 
-	case 0xD1:
-		// CALL
-		if execute {
-			newpc = jumpAddress
-			retpc := pc.Increment(instructionSize)
-			proc.Push(retpc)
-		} else {
-			newpc = pc.Increment(instructionSize)
-		}
+	return instruction, nil
+}
 
-	case 0xD2:
-		// RET
-		if execute {
-			newpc, err = proc.TopPop()
-			if err != nil {
-				return vStack, syscall, err
-			}
-		} else {
-			newpc = pc.Increment(instructionSize)
-		}
+// ExecuteOpcode - execute one opcode, with the handler for the opcode
+// an opcode its conditionals do not allow is stepped over
+func (proc *Processor) ExecuteOpcode(data *Page, opcode byte, vStack vputils.ByteStack, instruction InstructionDefinition, execute bool) (vputils.ByteStack, byte, error) {
+	pc := proc.PC()
 
-	default:
+	handler := opcodeHandlers[opcode]
+	if handler == nil {
 		// invalid opcode
 		s := fmt.Sprintf("Invalid opcode %02x at %s\n", opcode, pc.ToString())
 		return vStack, 0, errors.New(s)
 	}
 
+	syscall := byte(0)
+	newpc := pc.Increment(instruction.Size)
+
+	if execute {
+		var err error
+		vStack, syscall, newpc, err = handler(proc, data, vStack, instruction)
+		if err != nil {
+			return vStack, syscall, err
+		}
+	}
+
 	// advance to next instruction
-	err = proc.SetPC(newpc)
+	err := proc.SetPC(newpc)
 	if err != nil {
 		s := fmt.Sprintf("Invalid address %s for PC in main: %s", newpc.ToString(), err.Error())
 		return vStack, 0, errors.New(s)
@@ -920,9 +546,45 @@ func traceOpcode(pc vputils.Address, opcode byte, opcodeDef MnemonicTargetWidthA
 	return line
 }
 
-// ExecuteInstruction - execute an instruction
-func (proc *Processor) ExecuteInstruction(vStack vputils.ByteStack, codePage Page, dataPage *Page, trace bool) (vputils.ByteStack, byte, error) {
-	opcodeDefinitions := DefineOpcodes()
+// execute an instruction decoded at load time
+func (proc *Processor) executePredecoded(vStack vputils.ByteStack, predecoded PredecodedInstruction, trace bool) (vputils.ByteStack, byte, error) {
+	dataPage := &proc.Modules[proc.current].DataPage
+
+	pc1 := proc.PC()
+
+	proc.IncrementPC(len(predecoded.Conditionals))
+
+	execute, err := predecoded.Conditionals.Evaluate(proc.Flags)
+	if err != nil {
+		return vStack, 0, err
+	}
+
+	instruction, err := predecoded.complete(*dataPage, trace)
+	if err != nil {
+		message := err.Error() + " at PC " + pc1.ToString()
+		return vStack, 0, errors.New(message)
+	}
+
+	if trace {
+		line := traceOpcode(pc1, predecoded.Opcode, predecoded.Definition, proc.Flags, predecoded.Conditionals, instruction)
+		proc.traceLine(line)
+	}
+
+	return proc.ExecuteOpcode(dataPage, predecoded.Opcode, vStack, instruction, execute)
+}
+
+// ExecuteInstruction - execute an instruction in the current module
+// instructions decoded at load time skip the decoding
+func (proc *Processor) ExecuteInstruction(vStack vputils.ByteStack, trace bool) (vputils.ByteStack, byte, error) {
+	instructions := proc.Modules[proc.current].Instructions
+	address := proc.PC().Value
+
+	if address < len(instructions) && instructions[address].Valid {
+		return proc.executePredecoded(vStack, instructions[address], trace)
+	}
+
+	codePage := proc.CurrentModule().CodePage
+	dataPage := &proc.Modules[proc.current].DataPage
 
 	pc1 := proc.PC()
 
@@ -951,11 +613,14 @@ func (proc *Processor) ExecuteInstruction(vStack vputils.ByteStack, codePage Pag
 
 	// get instruction definition (opcode and arguments)
 	instruction, err := proc.DecodeInstruction(opcode, def, codePage, *dataPage)
-	vputils.CheckAndExit(err)
+	if err != nil {
+		message := err.Error() + " at PC " + pc1.ToString()
+		return vStack, 0, errors.New(message)
+	}
 
 	if trace {
 		line := traceOpcode(pc1, opcode, def, proc.Flags, conditionals, instruction)
-		fmt.Println(line)
+		proc.traceLine(line)
 	}
 
 	// execute instruction
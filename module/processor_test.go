@@ -0,0 +1,125 @@
+package module
+
+import (
+	"github.com/jfitz/virtual-processor/vputils"
+	"strings"
+	"testing"
+)
+
+// a module with the given code and data, and one-byte addresses
+func makeTestModule(code vputils.Vector, data vputils.Vector) Module {
+	codeProperties := []vputils.NameValue{
+		{"INSTRUCTION SET VERSION", InstructionSetVersion},
+		{"CODE ADDRESS WIDTH", "1"},
+		{"DATA ADDRESS WIDTH", "1"},
+	}
+
+	dataProperties := []vputils.NameValue{{"DATA ADDRESS WIDTH", "1"}}
+
+	return Module{
+		Properties:       []vputils.NameValue{},
+		CodePage:         Page{codeProperties, code, 1, 0},
+		Exports:          []vputils.NameValue{{"MAIN", "0"}},
+		DataPage:         Page{dataProperties, data, 1, 0},
+		ConstPage:        Page{dataProperties, vputils.Vector{}, 1, 0},
+		CodeAddressWidth: 1,
+		DataAddressWidth: 1,
+	}
+}
+
+// load a module, with or without its instructions decoded at load time
+func loadTestModule(t testing.TB, mod Module, predecode bool) []LoadedModule {
+	loaded, err := Load("test", mod, nil)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+
+	if !predecode {
+		loaded[0].Instructions = nil
+	}
+
+	return loaded
+}
+
+// run from address zero until EXIT, or an error
+func runTestModule(proc *Processor) error {
+	start, err := vputils.MakeAddress(0, 1, len(proc.CurrentModule().CodePage.Contents))
+	if err != nil {
+		return err
+	}
+
+	proc.SetPC(start)
+	vStack := vputils.ByteStack{}
+
+	for {
+		var syscall byte
+		vStack, syscall, err = proc.ExecuteInstruction(vStack, false)
+		if err != nil || syscall == 0x04 {
+			return err
+		}
+	}
+}
+
+// count a byte down to zero, with a conditional jump back
+func makeLoopModule() Module {
+	code := vputils.Vector{
+		0x31, 0x00, // DEC BYTE @00
+		0x11, 0x00, // FLAGS BYTE @00
+		0xE0, 0xE8, 0xD0, 0x00, // NOT ZERO JUMP >00
+		0x04, // EXIT
+	}
+
+	return makeTestModule(code, vputils.Vector{0})
+}
+
+func benchmarkLoop(b *testing.B, predecode bool) {
+	loaded := loadTestModule(b, makeLoopModule(), predecode)
+	proc := Processor{Modules: loaded}
+
+	for i := 0; i < b.N; i++ {
+		proc.Modules[0].DataPage.Contents[0] = 255
+
+		err := runTestModule(&proc)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecutePredecoded(b *testing.B) {
+	benchmarkLoop(b, true)
+}
+
+func BenchmarkExecuteDecodeEachStep(b *testing.B) {
+	benchmarkLoop(b, false)
+}
+
+func TestLoopCountsDown(t *testing.T) {
+	for _, predecode := range []bool{true, false} {
+		proc := Processor{Modules: loadTestModule(t, makeLoopModule(), predecode)}
+		proc.Modules[0].DataPage.Contents[0] = 5
+
+		err := runTestModule(&proc)
+		if err != nil {
+			t.Fatalf("predecode %v: %s", predecode, err)
+		}
+
+		if proc.Modules[0].DataPage.Contents[0] != 0 {
+			t.Errorf("predecode %v: counter is %d, want 0", predecode, proc.Modules[0].DataPage.Contents[0])
+		}
+	}
+}
+
+func TestOperandErrorIsReturned(t *testing.T) {
+	// PUSH BYTE @@00, where the pointer is outside the data
+	code := vputils.Vector{0x62, 0x00, 0x04}
+
+	for _, predecode := range []bool{true, false} {
+		proc := Processor{Modules: loadTestModule(t, makeTestModule(code, vputils.Vector{0xC8}), predecode)}
+
+		err := runTestModule(&proc)
+		if err == nil || !strings.Contains(err.Error(), "C8") {
+			t.Errorf("predecode %v: bad pointer gave %v, want an error naming address C8", predecode, err)
+		}
+	}
+}
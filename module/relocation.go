@@ -0,0 +1,192 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"errors"
+	"github.com/jfitz/virtual-processor/vputils"
+	"strconv"
+	"strings"
+)
+
+// Relocation - an address field in the code, and the segment it refers to
+type Relocation struct {
+	Offset  int
+	Segment string
+	Width   int
+}
+
+// MakeRelocation - build a relocation record for an address field
+func MakeRelocation(offset int, segment string, width int) Relocation {
+	return Relocation{offset, segment, width}
+}
+
+// ParseRelocation - parse a relocation record from the module table
+func ParseRelocation(nameValue vputils.NameValue) (Relocation, error) {
+	offset, err := strconv.Atoi(nameValue.Name)
+	if err != nil {
+		return Relocation{}, errors.New("Invalid relocation offset '" + nameValue.Name + "'")
+	}
+
+	fields := strings.Split(nameValue.Value, " ")
+	if len(fields) != 2 {
+		return Relocation{}, errors.New("Invalid relocation '" + nameValue.Value + "'")
+	}
+
+	segment := fields[0]
+	if segment != "CODE" && segment != "DATA" && segment != "IMPORT" {
+		return Relocation{}, errors.New("Invalid relocation segment '" + segment + "'")
+	}
+
+	width, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Relocation{}, errors.New("Invalid relocation width '" + fields[1] + "'")
+	}
+
+	return Relocation{offset, segment, width}, nil
+}
+
+// ToNameValue - convert to an entry for the module table
+func (relocation Relocation) ToNameValue() vputils.NameValue {
+	location := strconv.Itoa(relocation.Offset)
+	field := relocation.Segment + " " + strconv.Itoa(relocation.Width)
+
+	return vputils.NameValue{location, field}
+}
+
+// ReadField - read a little-endian address field
+func ReadField(code vputils.Vector, offset int, width int) (int, error) {
+	start, err := vputils.MakeAddress(offset, width, len(code))
+	if err != nil {
+		return 0, err
+	}
+
+	bytes, err := code.GetBytes(start, width)
+	if err != nil {
+		return 0, err
+	}
+
+	value := 0
+	for i := width; i > 0; i-- {
+		value *= 256
+		value += int(bytes[i-1])
+	}
+
+	return value, nil
+}
+
+// WriteField - write a little-endian address field
+func WriteField(code vputils.Vector, offset int, width int, value int) error {
+	if vputils.AddressWidth(value) > width {
+		return errors.New("Address " + strconv.Itoa(value) + " does not fit in field at " + strconv.Itoa(offset))
+	}
+
+	address, err := vputils.MakeAddress(value, width, value)
+	if err != nil {
+		return err
+	}
+
+	for i, b := range address.ToBytes() {
+		location, err := vputils.MakeAddress(offset+i, width, len(code))
+		if err != nil {
+			return err
+		}
+
+		err = code.PutByte(location, b)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Layout - where a module's code and the three parts of its data space start
+// a linker groups the data, reserved space, and constants of all its modules,
+// so each part has a base of its own
+type Layout struct {
+	CodeBase  int
+	DataBase  int
+	BSSBase   int
+	ConstBase int
+}
+
+// MakeLayout - place the code at codeBase, and the data space in order from dataBase
+func MakeLayout(mod Module, codeBase int, dataBase int) Layout {
+	bssBase := dataBase + len(mod.DataPage.Contents)
+	constBase := bssBase + mod.BSSSize
+
+	return Layout{codeBase, dataBase, bssBase, constBase}
+}
+
+// RelocateCode - move a code address into the layout
+func (layout Layout) RelocateCode(address int) int {
+	return layout.CodeBase + address
+}
+
+// move a data space address into the layout
+func (mod Module) relocateData(layout Layout, address int) int {
+	dataSize := len(mod.DataPage.Contents)
+
+	if address < dataSize {
+		return layout.DataBase + address
+	}
+
+	if address < dataSize+mod.BSSSize {
+		return layout.BSSBase + address - dataSize
+	}
+
+	return layout.ConstBase + address - dataSize - mod.BSSSize
+}
+
+// Rebase - move the addresses in a module to a layout
+// address fields in the code and exports change, offsets within the module do not,
+// and import indexes and external references are left for the linker
+func (mod Module) Rebase(layout Layout) (Module, error) {
+	code := make(vputils.Vector, len(mod.CodePage.Contents))
+	copy(code, mod.CodePage.Contents)
+
+	for _, nameValue := range mod.Relocations {
+		relocation, err := ParseRelocation(nameValue)
+		if err != nil {
+			return Module{}, err
+		}
+
+		if relocation.Segment == "IMPORT" {
+			continue
+		}
+
+		value, err := ReadField(code, relocation.Offset, relocation.Width)
+		if err != nil {
+			return Module{}, err
+		}
+
+		if relocation.Segment == "CODE" {
+			value = layout.RelocateCode(value)
+		} else {
+			value = mod.relocateData(layout, value)
+		}
+
+		err = WriteField(code, relocation.Offset, relocation.Width, value)
+		if err != nil {
+			return Module{}, err
+		}
+	}
+
+	exports := []vputils.NameValue{}
+	for _, nameValue := range mod.Exports {
+		address, err := strconv.Atoi(nameValue.Value)
+		if err != nil {
+			return Module{}, errors.New("Invalid address for symbol " + nameValue.Name)
+		}
+
+		exports = append(exports, vputils.NameValue{nameValue.Name, strconv.Itoa(layout.RelocateCode(address))})
+	}
+
+	rebased := mod
+	rebased.CodePage = Page{mod.CodePage.Properties, code, mod.CodePage.AddressWidth, 0}
+	rebased.Exports = exports
+
+	return rebased, nil
+}
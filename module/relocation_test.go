@@ -0,0 +1,197 @@
+package module
+
+import (
+	"github.com/jfitz/virtual-processor/vputils"
+	"strconv"
+	"testing"
+)
+
+func TestRelocationRoundTrip(t *testing.T) {
+	relocations := []Relocation{
+		MakeRelocation(3, "CODE", 1),
+		MakeRelocation(260, "DATA", 2),
+		MakeRelocation(7, "IMPORT", 1),
+	}
+
+	for _, relocation := range relocations {
+		nameValue := relocation.ToNameValue()
+
+		parsed, err := ParseRelocation(nameValue)
+		if err != nil {
+			t.Fatalf("ParseRelocation(%v): %s", nameValue, err)
+		}
+
+		if parsed != relocation {
+			t.Errorf("ParseRelocation(%v) = %v, want %v", nameValue, parsed, relocation)
+		}
+	}
+}
+
+func TestParseRelocationErrors(t *testing.T) {
+	invalids := []vputils.NameValue{
+		{"x", "CODE 1"},
+		{"3", "CODE"},
+		{"3", "CODE 1 2"},
+		{"3", "STACK 1"},
+		{"3", "DATA wide"},
+	}
+
+	for _, nameValue := range invalids {
+		_, err := ParseRelocation(nameValue)
+		if err == nil {
+			t.Errorf("ParseRelocation(%v) succeeded, want error", nameValue)
+		}
+	}
+}
+
+func TestReadWriteField(t *testing.T) {
+	code := make(vputils.Vector, 6)
+
+	err := WriteField(code, 1, 2, 0x1234)
+	if err != nil {
+		t.Fatalf("WriteField: %s", err)
+	}
+
+	expected := vputils.Vector{0x00, 0x34, 0x12, 0x00, 0x00, 0x00}
+	for i := range expected {
+		if code[i] != expected[i] {
+			t.Fatalf("WriteField wrote % X, want % X", []byte(code), []byte(expected))
+		}
+	}
+
+	value, err := ReadField(code, 1, 2)
+	if err != nil {
+		t.Fatalf("ReadField: %s", err)
+	}
+
+	if value != 0x1234 {
+		t.Errorf("ReadField = %04X, want 1234", value)
+	}
+}
+
+func TestWriteFieldTooWide(t *testing.T) {
+	code := make(vputils.Vector, 4)
+
+	err := WriteField(code, 0, 1, 0x100)
+	if err == nil {
+		t.Error("WriteField of 0100 into one byte succeeded, want error")
+	}
+}
+
+func TestWidenMovesJumpTargets(t *testing.T) {
+	mod, err := Read("../test/assembler/jump_z/ref/program.module")
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	wide, err := mod.Widen(2, 2)
+	if err != nil {
+		t.Fatalf("Widen: %s", err)
+	}
+
+	if wide.CodeAddressWidth != 2 || wide.DataAddressWidth != 2 {
+		t.Fatalf("Widen gave widths %d and %d, want 2 and 2", wide.CodeAddressWidth, wide.DataAddressWidth)
+	}
+
+	narrow, err := Disassemble(mod)
+	if err != nil {
+		t.Fatalf("Disassemble: %s", err)
+	}
+
+	widened, err := Disassemble(wide)
+	if err != nil {
+		t.Fatalf("Disassemble widened: %s", err)
+	}
+
+	if len(widened) != len(narrow) {
+		t.Fatalf("widened module has %d instructions, want %d", len(widened), len(narrow))
+	}
+
+	starts := make(map[int]int)
+	for i, instruction := range narrow {
+		starts[instruction.Address] = widened[i].Address
+	}
+
+	for i, instruction := range narrow {
+		if widened[i].Opcode != instruction.Opcode {
+			t.Errorf("instruction %d has opcode %02X, want %02X", i, widened[i].Opcode, instruction.Opcode)
+		}
+
+		if instruction.IsJump() {
+			want := starts[instruction.OperandValue()]
+			if widened[i].OperandValue() != want {
+				t.Errorf("jump %d targets %04X, want %04X", i, widened[i].OperandValue(), want)
+			}
+		}
+	}
+
+	if _, err := wide.Widen(1, 1); err == nil {
+		t.Error("narrowing a module succeeded, want error")
+	}
+}
+
+func TestRebaseMovesAddresses(t *testing.T) {
+	mod, err := Read("../test/assembler/bss/ref/program.module")
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	// data, reserved space, and constants each at a base of their own
+	layout := Layout{0x10, 0x100, 0x200, 0x800}
+
+	rebased, err := mod.Rebase(layout)
+	if err != nil {
+		t.Fatalf("Rebase: %s", err)
+	}
+
+	dataSize := len(mod.DataPage.Contents)
+
+	for _, nameValue := range mod.Relocations {
+		relocation, _ := ParseRelocation(nameValue)
+
+		before, _ := ReadField(mod.CodePage.Contents, relocation.Offset, relocation.Width)
+		after, _ := ReadField(rebased.CodePage.Contents, relocation.Offset, relocation.Width)
+
+		want := before + layout.CodeBase
+		if relocation.Segment == "DATA" {
+			switch {
+			case before < dataSize:
+				want = before + layout.DataBase
+			case before < dataSize+mod.BSSSize:
+				want = before - dataSize + layout.BSSBase
+			default:
+				want = before - dataSize - mod.BSSSize + layout.ConstBase
+			}
+		}
+
+		if after != want {
+			t.Errorf("%s field at %d is %X, want %X", relocation.Segment, relocation.Offset, after, want)
+		}
+	}
+
+	for i, nameValue := range rebased.Exports {
+		address, _ := strconv.Atoi(mod.Exports[i].Value)
+		if nameValue.Value != strconv.Itoa(address+layout.CodeBase) {
+			t.Errorf("export %s is at %s, want %d", nameValue.Name, nameValue.Value, address+layout.CodeBase)
+		}
+	}
+
+	// loaded as one module, the data space stays in order
+	loaded, err := mod.Rebase(MakeLayout(mod, 0, 0x100))
+	if err != nil {
+		t.Fatalf("Rebase: %s", err)
+	}
+
+	for _, nameValue := range mod.Relocations {
+		relocation, _ := ParseRelocation(nameValue)
+		if relocation.Segment != "DATA" {
+			continue
+		}
+
+		before, _ := ReadField(mod.CodePage.Contents, relocation.Offset, relocation.Width)
+		after, _ := ReadField(loaded.CodePage.Contents, relocation.Offset, relocation.Width)
+		if after != before+0x100 {
+			t.Errorf("DATA field at %d is %X, want %X", relocation.Offset, after, before+0x100)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Package module for virtual-processor
+*/
+package module
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jfitz/virtual-processor/vputils"
+	"strconv"
+)
+
+// set the value of a property, if the table has it
+func setProperty(properties []vputils.NameValue, name string, value string) []vputils.NameValue {
+	updated := []vputils.NameValue{}
+
+	for _, nameValue := range properties {
+		if nameValue.Name == name {
+			nameValue.Value = value
+		}
+
+		updated = append(updated, nameValue)
+	}
+
+	return updated
+}
+
+// Widen - re-encode a module with wider code and data addresses
+// each address operand grows, so instructions move and jump targets,
+// exports, externals, and relocations are adjusted to match
+func (mod Module) Widen(codeAddressWidth int, dataAddressWidth int) (Module, error) {
+	if codeAddressWidth < mod.CodeAddressWidth || dataAddressWidth < mod.DataAddressWidth {
+		return Module{}, errors.New("Cannot narrow module addresses")
+	}
+
+	if codeAddressWidth == mod.CodeAddressWidth && dataAddressWidth == mod.DataAddressWidth {
+		return mod, nil
+	}
+
+	instructions, err := Disassemble(mod)
+	if err != nil {
+		return Module{}, err
+	}
+
+	wide := mod
+	wide.CodeAddressWidth = codeAddressWidth
+	wide.DataAddressWidth = dataAddressWidth
+
+	// the new address of each instruction, and of each operand
+	starts := make(map[int]int)
+	operands := make(map[int]int)
+	operandSizes := make(map[int]int)
+
+	address := 0
+	for _, instruction := range instructions {
+		starts[instruction.Address] = address
+
+		oldOperand := instruction.Address + len(instruction.Conditionals) + 1
+		newOperand := address + len(instruction.Conditionals) + 1
+		size := operandSize(instruction.Definition, wide)
+
+		operands[oldOperand] = newOperand
+		operandSizes[newOperand] = size
+
+		address = newOperand + size
+	}
+
+	externals := make(map[int]bool)
+	for _, nameValue := range mod.Externals {
+		offset, err := strconv.Atoi(nameValue.Value)
+		if err != nil {
+			return Module{}, errors.New("Invalid reference to " + nameValue.Name)
+		}
+
+		externals[offset] = true
+	}
+
+	code := vputils.Vector{}
+	for _, instruction := range instructions {
+		code = append(code, instruction.Conditionals...)
+		code = append(code, instruction.Opcode)
+
+		offset := len(code)
+
+		// operands are little-endian, so zeros on the end keep their values
+		field := make(vputils.Vector, operandSizes[offset])
+		copy(field, instruction.Operand)
+		code = append(code, field...)
+
+		// jump targets move with the instructions, unresolved externals stay zero
+		if instruction.IsJump() && !externals[instruction.Address+len(instruction.Conditionals)+1] {
+			value := instruction.OperandValue()
+
+			target, ok := starts[value]
+			if !ok {
+				return Module{}, fmt.Errorf("Jump at %02X to %02X is not to an instruction", instruction.Address, value)
+			}
+
+			err = WriteField(code, offset, len(field), target)
+			if err != nil {
+				return Module{}, err
+			}
+		}
+	}
+
+	relocations := []vputils.NameValue{}
+	for _, nameValue := range mod.Relocations {
+		relocation, err := ParseRelocation(nameValue)
+		if err != nil {
+			return Module{}, err
+		}
+
+		offset, ok := operands[relocation.Offset]
+		if !ok {
+			return Module{}, fmt.Errorf("Relocation at %02X is not an operand", relocation.Offset)
+		}
+
+		moved := MakeRelocation(offset, relocation.Segment, operandSizes[offset])
+		relocations = append(relocations, moved.ToNameValue())
+	}
+
+	moved := []vputils.NameValue{}
+	for _, nameValue := range mod.Externals {
+		offset, _ := strconv.Atoi(nameValue.Value)
+
+		newOffset, ok := operands[offset]
+		if !ok {
+			return Module{}, errors.New("Reference to " + nameValue.Name + " is not an operand")
+		}
+
+		moved = append(moved, vputils.NameValue{nameValue.Name, strconv.Itoa(newOffset)})
+	}
+
+	exports := []vputils.NameValue{}
+	for _, nameValue := range mod.Exports {
+		export, err := strconv.Atoi(nameValue.Value)
+		if err != nil {
+			return Module{}, errors.New("Invalid address for symbol " + nameValue.Name)
+		}
+
+		start, ok := starts[export]
+		if !ok {
+			return Module{}, errors.New("Symbol " + nameValue.Name + " is not at an instruction")
+		}
+
+		exports = append(exports, vputils.NameValue{nameValue.Name, strconv.Itoa(start)})
+	}
+
+	caws := strconv.Itoa(codeAddressWidth)
+	daws := strconv.Itoa(dataAddressWidth)
+
+	codeProperties := setProperty(mod.CodePage.Properties, "CODE ADDRESS WIDTH", caws)
+	codeProperties = setProperty(codeProperties, "DATA ADDRESS WIDTH", daws)
+	dataProperties := setProperty(mod.DataPage.Properties, "DATA ADDRESS WIDTH", daws)
+	constProperties := setProperty(mod.ConstPage.Properties, "DATA ADDRESS WIDTH", daws)
+
+	wide.CodePage = Page{codeProperties, code, codeAddressWidth, 0}
+	wide.DataPage = Page{dataProperties, mod.DataPage.Contents, dataAddressWidth, 0}
+	wide.ConstPage = Page{constProperties, mod.ConstPage.Contents, dataAddressWidth, len(mod.ConstPage.Contents)}
+	wide.Exports = exports
+	wide.Externals = moved
+	wide.Relocations = relocations
+
+	return wide, nil
+}
@@ -7,54 +7,50 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/jfitz/virtual-processor/kernel"
 	"github.com/jfitz/virtual-processor/module"
 	"github.com/jfitz/virtual-processor/vputils"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func kernelCall(vStack vputils.ByteStack) vputils.ByteStack {
-	fname, vStack := vStack.PopString()
+// the process exit code when the runner fails, or the program faults
+// a program exits with its own status, from 0 to maxExitStatus
+const runnerFailure = 125
 
-	// dispatch to function
-	bytes := []byte{}
-	s := ""
-	err := errors.New("")
-
-	switch fname {
-
-	case "out_b":
-		bytes, vStack, err = vStack.PopByte(1)
-		vputils.CheckAndPanic(err)
-
-		fmt.Print(string(bytes[0]))
-
-	case "out_s":
-		s, vStack = vStack.PopString()
-
-		fmt.Print(s)
-
-	default:
-		err = errors.New("Unknown kernel call to function '" + fname + "'")
-		vputils.CheckAndExit(err)
+const maxExitStatus = 124
 
+// checkAndExit - stop the runner on an error
+func checkAndExit(err error) {
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(runnerFailure)
 	}
+}
 
-	// return to module
-	return vStack
+// checkPrintAndExit - stop the runner on an error, with a message
+func checkPrintAndExit(err error, message string) {
+	if err != nil {
+		fmt.Println(err.Error() + " " + message)
+		os.Exit(runnerFailure)
+	}
 }
 
-func outCall(vStack vputils.ByteStack, trace bool) vputils.ByteStack {
+func outCall(kern *kernel.Kernel, vStack vputils.ByteStack) (vputils.ByteStack, error) {
 	bytes, vStack, err := vStack.PopByte(1)
-	vputils.CheckAndPanic(err)
-
-	fmt.Print(string(bytes[0]))
+	if err != nil {
+		return vStack, err
+	}
 
-	if trace {
-		fmt.Println()
+	err = kern.Print(string(bytes[0]))
+	if err != nil {
+		return vStack, err
 	}
 
-	return vStack
+	return vStack, nil
 }
 
 func traceValueStack(stack vputils.ByteStack) string {
@@ -75,7 +71,8 @@ func traceHalt(pc vputils.Address) string {
 	return line
 }
 
-func executeCode(mod module.Module, proc module.Processor, startAddress vputils.Address, trace bool) error {
+// run a program until it stops, and return its exit status
+func executeCode(proc module.Processor, kern *kernel.Kernel, startAddress vputils.Address, trace bool) (byte, error) {
 	// initialize virtual processor
 	vStack := make(vputils.ByteStack, 0) // value stack
 
@@ -83,21 +80,21 @@ func executeCode(mod module.Module, proc module.Processor, startAddress vputils.
 	err := proc.SetPC(startAddress)
 	if err != nil {
 		s := fmt.Sprintf("Invalid start address %s for main: %s", startAddress.ToString(), err.Error())
-		return errors.New(s)
+		return 0, errors.New(s)
 	}
 
 	// trace
 	if trace {
-		fmt.Println("Execution started at ", startAddress.ToString())
+		fmt.Fprintln(proc.TraceOutput, "Execution started at ", startAddress.ToString())
 	}
 
 	halt := false
 	syscall := byte(0)
 
 	for !halt {
-		vStack, syscall, err = proc.ExecuteInstruction(vStack, mod.CodePage, &mod.DataPage, trace)
+		vStack, syscall, err = proc.ExecuteInstruction(vStack, trace)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
 		// process the requested runner call
@@ -108,49 +105,94 @@ func executeCode(mod module.Module, proc module.Processor, startAddress vputils.
 			halt = true
 
 		case 0x05:
-			vStack = kernelCall(vStack)
+			vStack, err = kern.Call(vStack)
+			if err != nil {
+				return 0, err
+			}
 
 		case 0x08:
-			vStack = outCall(vStack, trace)
+			vStack, err = outCall(kern, vStack)
+			if err != nil {
+				return 0, err
+			}
 
 		}
 
 		// display value stack
 		if trace {
 			line := traceValueStack(vStack)
-			fmt.Println(line)
+			fmt.Fprintln(proc.TraceOutput, line)
 		}
 	}
 
 	// display halt information
 	if trace {
 		line := traceHalt(proc.PC())
-		fmt.Println(line)
+		fmt.Fprintln(proc.TraceOutput, line)
 	}
 
-	return nil
+	if proc.ExitStatus > maxExitStatus {
+		return 0, fmt.Errorf("Exit status %d is reserved for the runner", proc.ExitStatus)
+	}
+
+	return proc.ExitStatus, nil
 }
 
 func main() {
 	startSymbolPtr := flag.String("start", "MAIN", "Start execution at symbol.")
 	tracePtr := flag.Bool("trace", false, "Display trace during execution.")
+	traceFilePtr := flag.String("trace-file", "", "Write the trace to a file.")
+	outputPtr := flag.String("output", "", "Write console output to a file.")
+	libraryPathPtr := flag.String("library-path", "", "Directories to search for library modules.")
+	inputPtr := flag.String("input", "", "Read console input from a file.")
+	fileDirectoryPtr := flag.String("file-directory", "", "Confine file access to a directory.")
+	seedPtr := flag.String("seed", "", "Seed for random numbers, for the same numbers on every run.")
+	clockPtr := flag.String("clock", "", "Stop the clock at a time, as YYYY-MM-DDTHH:MM:SS.")
+	allowPtr := flag.String("allow", "", "Allow only these kernel function groups, separated by commas.")
+	denyPtr := flag.String("deny", "", "Deny these kernel function groups, separated by commas.")
+	maxOutputPtr := flag.String("max-output", "", "Stop the program after printing this many bytes.")
+	maxFilesPtr := flag.String("max-files", "", "Limit the files the program can have open at once.")
+	maxFileBytesPtr := flag.String("max-file-bytes", "", "Stop the program when it writes more bytes to files.")
 
 	flag.Parse()
 
 	startSymbol := *startSymbolPtr
 	trace := *tracePtr
+	traceFile := *traceFilePtr
+	outputFile := *outputPtr
+	libraryPath := *libraryPathPtr
+	inputFile := *inputPtr
+	fileDirectory := *fileDirectoryPtr
+	seed := *seedPtr
+	clock := *clockPtr
+	allow := *allowPtr
+	deny := *denyPtr
+	maxOutput := *maxOutputPtr
+	maxFiles := *maxFilesPtr
+	maxFileBytes := *maxFileBytesPtr
 
 	args := flag.Args()
 
 	if len(args) == 0 {
 		fmt.Println("No module file specified")
-		os.Exit(1)
+		os.Exit(runnerFailure)
 	}
 
 	moduleFile := args[0]
 
 	mod, err := module.Read(moduleFile)
-	vputils.CheckAndExit(err)
+	checkAndExit(err)
+
+	if len(mod.Externals) > 0 {
+		reported := make(map[string]bool)
+		for _, nameValue := range mod.Externals {
+			if !reported[nameValue.Name] {
+				fmt.Println("Unresolved external symbol " + nameValue.Name)
+				reported[nameValue.Name] = true
+			}
+		}
+		os.Exit(runnerFailure)
+	}
 
 	exports := mod.Exports
 	codeAddressWidth := mod.CodeAddressWidth
@@ -161,19 +203,119 @@ func main() {
 		if nameValue.Name == startSymbol {
 			startAddressFound = true
 			startAddressInt, err = strconv.Atoi(nameValue.Value)
-			vputils.CheckPrintAndExit(err, "Invalid start address")
+			checkPrintAndExit(err, "Invalid start address")
 		}
 	}
 
 	if !startAddressFound {
 		fmt.Println("Starting symbol " + startSymbol + " not found")
-		os.Exit(2)
+		os.Exit(runnerFailure)
 	}
 
 	startAddress, err := vputils.MakeAddress(startAddressInt, codeAddressWidth, len(mod.CodePage.Contents))
-	vputils.CheckAndExit(err)
+	checkAndExit(err)
+
+	// libraries are found in the search path, or beside the module
+	searchPath := filepath.SplitList(libraryPath)
+	if len(searchPath) == 0 {
+		searchPath = []string{filepath.Dir(moduleFile)}
+	}
+
+	loaded, err := module.Load(module.ModuleName(moduleFile), mod, searchPath)
+	checkAndExit(err)
+
+	// the trace never shares a stream with the program's output
+	proc := module.Processor{Modules: loaded, TraceOutput: os.Stderr}
+	kern, err := kernel.MakeKernel()
+	checkAndExit(err)
+
+	if len(inputFile) > 0 {
+		f, err := os.Open(inputFile)
+		checkAndExit(err)
+
+		defer f.Close()
+
+		kern.SetInput(f)
+	}
+
+	if len(outputFile) > 0 {
+		f, err := os.Create(outputFile)
+		checkAndExit(err)
+
+		defer f.Close()
+
+		kern.SetOutput(f)
+	}
+
+	if len(traceFile) > 0 {
+		f, err := os.Create(traceFile)
+		checkAndExit(err)
+
+		defer f.Close()
+
+		proc.TraceOutput = f
+	}
+
+	if len(fileDirectory) > 0 {
+		kern.SetFileDirectory(fileDirectory)
+	}
+
+	// the arguments after the module name are for the program
+	kern.SetArguments(args)
+
+	if len(seed) > 0 {
+		seedInt, err := strconv.ParseInt(seed, 10, 64)
+		checkPrintAndExit(err, "Invalid seed")
+
+		kern.SetSeed(seedInt)
+	}
+
+	if len(clock) > 0 {
+		clockTime, err := time.Parse("2006-01-02T15:04:05", clock)
+		checkPrintAndExit(err, "Invalid clock time")
+
+		kern.SetClock(clockTime)
+	}
+
+	if len(allow) > 0 {
+		err = kern.AllowOnly(strings.Split(allow, ","))
+		checkAndExit(err)
+	}
+
+	if len(deny) > 0 {
+		for _, group := range strings.Split(deny, ",") {
+			err = kern.Deny(group)
+			checkAndExit(err)
+		}
+	}
+
+	if len(maxOutput) > 0 {
+		limit, err := strconv.Atoi(maxOutput)
+		checkPrintAndExit(err, "Invalid output limit")
+
+		err = kern.SetOutputLimit(limit)
+		checkAndExit(err)
+	}
+
+	if len(maxFiles) > 0 {
+		limit, err := strconv.Atoi(maxFiles)
+		checkPrintAndExit(err, "Invalid file limit")
+
+		err = kern.SetFileLimit(limit)
+		checkAndExit(err)
+	}
+
+	if len(maxFileBytes) > 0 {
+		limit, err := strconv.Atoi(maxFileBytes)
+		checkPrintAndExit(err, "Invalid file bytes limit")
+
+		err = kern.SetFileBytesLimit(limit)
+		checkAndExit(err)
+	}
+
+	status, err := executeCode(proc, kern, startAddress, trace)
+	kern.CloseFiles()
+	checkAndExit(err)
 
-	proc := module.Processor{}
-	err = executeCode(mod, proc, startAddress, trace)
-	vputils.CheckAndExit(err)
+	os.Exit(int(status))
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/jfitz/virtual-processor/kernel"
+	"github.com/jfitz/virtual-processor/module"
+	"github.com/jfitz/virtual-processor/vputils"
+	"testing"
+)
+
+func TestKcallHostFunction(t *testing.T) {
+	kern, err := kernel.MakeKernel()
+	if err != nil {
+		t.Fatalf("MakeKernel: %s", err)
+	}
+
+	twice := func(args []interface{}) ([]interface{}, error) {
+		return []interface{}{args[0].(byte) * 2}, nil
+	}
+
+	err = kern.Register(kernel.Function{"twice", "host", []string{"BYTE"}, []string{"BYTE"}, twice})
+	if err != nil {
+		t.Fatalf("Register: %s", err)
+	}
+
+	// passes a byte to twice and stops with the result
+	mod, err := module.Read("../test/assembler/kcall_host/ref/program.module")
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	loaded, err := module.Load("program", mod, nil)
+	if err != nil {
+		t.Fatalf("Load: %s", err)
+	}
+
+	proc := module.Processor{Modules: loaded}
+
+	start, err := vputils.MakeAddress(0, 1, len(loaded[0].CodePage.Contents))
+	if err != nil {
+		t.Fatalf("MakeAddress: %s", err)
+	}
+
+	status, err := executeCode(proc, kern, start, false)
+	if err != nil {
+		t.Fatalf("executeCode: %s", err)
+	}
+
+	if status != 0x2A {
+		t.Errorf("exit status %02X, want 2A", status)
+	}
+}
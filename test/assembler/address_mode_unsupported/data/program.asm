@@ -0,0 +1,6 @@
+MESSAGE:	STRING	"Hello"
+POINTER:	BYTE	0
+
+MAIN:	PUSH STRING	@@POINTER
+	KCALL	OUT_S
+	EXIT
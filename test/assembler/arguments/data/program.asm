@@ -0,0 +1,48 @@
+ARG_COUNT:	STRING	"arg_count"
+ARG:	STRING	"arg"
+ENV:	STRING	"env"
+OUT_S:	STRING	"out_s"
+OUT_I16:	STRING	"out_i16"
+UNSET:	STRING	"VP_UNSET_VARIABLE"
+
+MAIN:	PUSH STRING	@ARG_COUNT
+	KCALL
+	PUSH STRING	@OUT_I16
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH I16	1
+	PUSH STRING	@ARG
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH I16	2
+	PUSH STRING	@ARG
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH I16	3
+	PUSH STRING	@ARG
+	KCALL
+	PUSH BYTE	48
+	ADD BYTE
+	OUT
+	POP BYTE
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@UNSET
+	PUSH STRING	@ENV
+	KCALL
+	PUSH BYTE	48
+	ADD BYTE
+	OUT
+	POP BYTE
+	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,5 @@
+ptr:	BYTE	200
+
+MAIN:	PUSH BYTE	@@ptr
+	OUT
+	EXIT
@@ -0,0 +1,15 @@
+NEWLINE:	BYTE	10
+BUFFER:	RESERVE	1000
+LETTER:	RESERVE	1
+MESSAGE:	CONST STRING	"Buffer"
+
+MAIN:	PUSH BYTE	@LETTER
+	FLAGS BYTE
+	NOT ZERO JUMP	FAIL
+	PUSH BYTE	66
+	POP BYTE	@LETTER
+	PUSH BYTE	@LETTER
+	OUT
+	PUSH BYTE	@NEWLINE
+	OUT
+FAIL:	EXIT
@@ -0,0 +1,13 @@
+MESSAGE:	CONST STRING	"Hello, world!"
+COUNT:	BYTE	0
+
+MAIN:	PUSH STRING	@MESSAGE
+	POP BYTE	@COUNT
+LOOP:	FLAGS BYTE
+	ZERO JUMP	NEWLINE
+	OUT
+	JUMP	LOOP
+
+NEWLINE:	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,5 @@
+MESSAGE:	CONST STRING	"Hello, world!"
+
+MAIN:	PUSH BYTE	74
+	POP BYTE	@MESSAGE
+	EXIT
@@ -0,0 +1,14 @@
+PADDING:	STRING	"Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore."
+MESSAGE:	STRING	"Hello, world!"
+COUNT:	BYTE	0
+
+MAIN:	PUSH STRING	@MESSAGE
+	POP BYTE	@COUNT
+LOOP:	FLAGS BYTE
+	ZERO JUMP	NEWLINE
+	OUT
+	JUMP	LOOP
+
+NEWLINE:	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,5 @@
+MAIN:	PUSH BYTE	0
+	PUSH BYTE	144
+	DIV BYTE
+	OUT
+	EXIT
@@ -0,0 +1 @@
+MAIN:	EXIT BYTE	200
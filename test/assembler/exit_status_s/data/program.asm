@@ -0,0 +1,2 @@
+MAIN:	PUSH BYTE	7
+	EXIT BYTE
@@ -0,0 +1,5 @@
+MAIN:	PUSH BYTE	72
+	OUT
+	PUSH BYTE	10
+	OUT
+	EXIT BYTE	3
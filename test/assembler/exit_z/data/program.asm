@@ -0,0 +1,7 @@
+MAIN:	PUSH BYTE	1
+	FLAGS BYTE
+	POP BYTE
+	ZERO EXIT
+	PUSH BYTE	72
+	OUT
+	EXIT
@@ -0,0 +1,6 @@
+	EXTERNAL	PRINT_S
+
+MAIN:	CALL	PRINT_S
+	EXIT
+
+PRINT_S:	RET
@@ -0,0 +1,2 @@
+MAIN:	CALL	PRINT_S
+	EXIT
@@ -0,0 +1,105 @@
+NAME:	STRING	"notes.txt"
+MODE_OUT:	STRING	"OUTPUT"
+MODE_IN:	STRING	"INPUT"
+MODE_RANDOM:	STRING	"RANDOM"
+LINE1:	STRING	"first"
+LINE2:	STRING	"second"
+F_OPEN:	STRING	"f_open"
+F_CLOSE:	STRING	"f_close"
+F_WRITE_S:	STRING	"f_write_s"
+F_WRITE_B:	STRING	"f_write_b"
+F_READ_LINE:	STRING	"f_read_line"
+F_SEEK:	STRING	"f_seek"
+OUT_S:	STRING	"out_s"
+handle:	BYTE	0
+
+# write two lines
+MAIN:	PUSH STRING	@MODE_OUT
+	PUSH STRING	@NAME
+	PUSH STRING	@F_OPEN
+	KCALL
+	POP BYTE
+	POP BYTE	@handle
+	PUSH STRING	@LINE1
+	PUSH BYTE	@handle
+	PUSH STRING	@F_WRITE_S
+	KCALL
+	POP BYTE
+	PUSH BYTE	10
+	PUSH BYTE	@handle
+	PUSH STRING	@F_WRITE_B
+	KCALL
+	POP BYTE
+	PUSH STRING	@LINE2
+	PUSH BYTE	@handle
+	PUSH STRING	@F_WRITE_S
+	KCALL
+	POP BYTE
+	PUSH BYTE	10
+	PUSH BYTE	@handle
+	PUSH STRING	@F_WRITE_B
+	KCALL
+	POP BYTE
+	PUSH BYTE	@handle
+	PUSH STRING	@F_CLOSE
+	KCALL
+
+# read the lines back
+	PUSH STRING	@MODE_IN
+	PUSH STRING	@NAME
+	PUSH STRING	@F_OPEN
+	KCALL
+	POP BYTE
+	POP BYTE	@handle
+read:	PUSH BYTE	@handle
+	PUSH STRING	@F_READ_LINE
+	KCALL
+	FLAGS BYTE
+	POP BYTE
+	ZERO JUMP	closed
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	JUMP	read
+closed:	POP BYTE
+	PUSH BYTE	@handle
+	PUSH STRING	@F_CLOSE
+	KCALL
+
+# change one byte and read the first line
+	PUSH STRING	@MODE_RANDOM
+	PUSH STRING	@NAME
+	PUSH STRING	@F_OPEN
+	KCALL
+	POP BYTE
+	POP BYTE	@handle
+	PUSH I16	0
+	PUSH I16	1
+	PUSH BYTE	@handle
+	PUSH STRING	@F_SEEK
+	KCALL
+	POP BYTE
+	PUSH BYTE	69
+	PUSH BYTE	@handle
+	PUSH STRING	@F_WRITE_B
+	KCALL
+	POP BYTE
+	PUSH I16	0
+	PUSH I16	0
+	PUSH BYTE	@handle
+	PUSH STRING	@F_SEEK
+	KCALL
+	POP BYTE
+	PUSH BYTE	@handle
+	PUSH STRING	@F_READ_LINE
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH BYTE	@handle
+	PUSH STRING	@F_CLOSE
+	KCALL
+	EXIT
@@ -0,0 +1,9 @@
+NAME:	STRING	"../secret.txt"
+MODE_IN:	STRING	"INPUT"
+F_OPEN:	STRING	"f_open"
+
+MAIN:	PUSH STRING	@MODE_IN
+	PUSH STRING	@NAME
+	PUSH STRING	@F_OPEN
+	KCALL
+	EXIT
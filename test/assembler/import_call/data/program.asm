@@ -0,0 +1,10 @@
+# characters to print
+letter:	CONST BYTE	105
+
+# main program, using library routines
+MAIN:	PUSH BYTE	72
+	CALL	stdlib.PRINT_B
+	PUSH BYTE	@letter
+	CALL	stdlib.PRINT_B
+	CALL	stdlib.PRINT_NL
+	EXIT
@@ -0,0 +1,13 @@
+IN_B:	STRING	"in_b"
+OUT_B:	STRING	"out_b"
+
+MAIN:	PUSH STRING	@IN_B
+	KCALL
+	FLAGS BYTE
+	POP BYTE
+	ZERO JUMP	done
+	PUSH STRING	@OUT_B
+	KCALL
+	JUMP	MAIN
+done:	POP BYTE
+	EXIT
@@ -0,0 +1,11 @@
+IN_I16:	STRING	"in_i16"
+
+MAIN:	PUSH STRING	@IN_I16
+	KCALL
+	PUSH STRING	@IN_I16
+	KCALL
+	PUSH STRING	@IN_I16
+	KCALL
+	PUSH STRING	@IN_I16
+	KCALL
+	EXIT
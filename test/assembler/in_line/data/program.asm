@@ -0,0 +1,15 @@
+IN_LINE:	STRING	"in_line"
+OUT_S:	STRING	"out_s"
+
+MAIN:	PUSH STRING	@IN_LINE
+	KCALL
+	FLAGS BYTE
+	POP BYTE
+	ZERO JUMP	done
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	JUMP	MAIN
+done:	POP BYTE
+	EXIT
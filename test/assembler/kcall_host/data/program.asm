@@ -0,0 +1,8 @@
+# pass a byte to the host function twice, and stop with the result
+# the runner has no twice, it is registered by runner_test.go
+TWICE:	STRING	"twice"
+
+MAIN:	PUSH BYTE	21
+	PUSH STRING	@TWICE
+	KCALL
+	EXIT BYTE
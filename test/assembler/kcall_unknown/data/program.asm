@@ -0,0 +1,5 @@
+BEEP:	STRING	"beep"
+
+MAIN:	PUSH STRING	@BEEP
+	KCALL
+	EXIT
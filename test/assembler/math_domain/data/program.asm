@@ -0,0 +1,22 @@
+SQR:	STRING	"sqr"
+S_TO_F64:	STRING	"s_to_f64"
+OUT_S:	STRING	"out_s"
+MINUS_ONE:	STRING	"-1"
+MESSAGE:	STRING	"No square root"
+
+MAIN:	PUSH STRING	@MINUS_ONE
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@SQR
+	KCALL
+	PUSH BYTE	1
+	CMP BYTE
+	ZERO JUMP	done
+	PUSH STRING	@MESSAGE
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	EXIT BYTE	2
+done:	EXIT
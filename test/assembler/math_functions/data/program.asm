@@ -0,0 +1,83 @@
+OUT_F64:	STRING	"out_f64"
+S_TO_F64:	STRING	"s_to_f64"
+SQR:	STRING	"sqr"
+INT:	STRING	"int"
+SGN:	STRING	"sgn"
+ABS:	STRING	"abs"
+EXP:	STRING	"exp"
+ATN:	STRING	"atn"
+COS:	STRING	"cos"
+N_SQR:	STRING	"16"
+N_INT:	STRING	"-2.5"
+N_SGN:	STRING	"-7"
+N_ABS:	STRING	"-7"
+N_EXP:	STRING	"1"
+N_ATN:	STRING	"1"
+N_COS:	STRING	"0"
+
+MAIN:	PUSH STRING	@N_SQR
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@SQR
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@N_INT
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@INT
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@N_SGN
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@SGN
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@N_ABS
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@ABS
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@N_EXP
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@EXP
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@N_ATN
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@ATN
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@N_COS
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@COS
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,11 @@
+OUT_S:	STRING	"out_s"
+HELLO:	STRING	"Hello, "
+
+MAIN:	PUSH STRING	@HELLO
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	87
+	OUT
+	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,8 @@
+POINTER:	BYTE	1
+TARGET:	BYTE	0
+
+MAIN:	PUSH BYTE	72
+	POP BYTE	@@POINTER
+	PUSH BYTE	@TARGET
+	OUT
+	EXIT
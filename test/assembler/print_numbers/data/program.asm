@@ -0,0 +1,44 @@
+OUT_I16:	STRING	"out_i16"
+OUT_F64:	STRING	"out_f64"
+OUT_USING:	STRING	"out_using"
+OUT_S:	STRING	"out_s"
+S_TO_F64:	STRING	"s_to_f64"
+I16_TO_F64:	STRING	"i16_to_f64"
+F64_TO_S:	STRING	"f64_to_s"
+PI:	STRING	"3.14159"
+AMOUNT:	STRING	"-1234.5"
+MASK:	STRING	"Total: #,###.##-"
+
+MAIN:	PUSH I16	42
+	PUSH STRING	@OUT_I16
+	KCALL
+	PUSH I16	65494
+	PUSH STRING	@OUT_I16
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@PI
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH I16	7
+	PUSH STRING	@I16_TO_F64
+	KCALL
+	PUSH STRING	@F64_TO_S
+	KCALL
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@AMOUNT
+	PUSH STRING	@S_TO_F64
+	KCALL
+	POP BYTE
+	PUSH STRING	@MASK
+	PUSH STRING	@OUT_USING
+	KCALL
+	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,12 @@
+# two bytes, read together as one I16
+LOW:	BYTE	72
+HIGH:	BYTE	58
+POINTER:	BYTE	0
+
+MAIN:	PUSH I16	@LOW
+	OUT
+	OUT
+	PUSH I16	@@POINTER
+	OUT
+	OUT
+	EXIT
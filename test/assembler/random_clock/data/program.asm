@@ -0,0 +1,45 @@
+RND:	STRING	"rnd"
+RANDOMIZE:	STRING	"randomize"
+TIME:	STRING	"time"
+DATE:	STRING	"date"
+TIMER:	STRING	"timer"
+OUT_S:	STRING	"out_s"
+OUT_F64:	STRING	"out_f64"
+
+MAIN:	PUSH STRING	@RND
+	KCALL
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH STRING	@RND
+	KCALL
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@TIMER
+	KCALL
+	PUSH STRING	@RANDOMIZE
+	KCALL
+	PUSH STRING	@RND
+	KCALL
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@DATE
+	KCALL
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	32
+	OUT
+	PUSH STRING	@TIME
+	KCALL
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH STRING	@TIMER
+	KCALL
+	PUSH STRING	@OUT_F64
+	KCALL
+	PUSH BYTE	10
+	OUT
+	EXIT
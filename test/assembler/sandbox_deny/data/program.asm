@@ -0,0 +1,16 @@
+F_OPEN:	STRING	"f_open"
+OUT_S:	STRING	"out_s"
+NAME:	STRING	"secret.txt"
+MODE:	STRING	"INPUT"
+HELLO:	STRING	"Hello"
+
+MAIN:	PUSH STRING	@HELLO
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@MODE
+	PUSH STRING	@NAME
+	PUSH STRING	@F_OPEN
+	KCALL
+	EXIT
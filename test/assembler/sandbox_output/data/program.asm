@@ -0,0 +1,12 @@
+OUT_S:	STRING	"out_s"
+HELLO:	STRING	"Hello"
+
+MAIN:	PUSH STRING	@HELLO
+	PUSH STRING	@OUT_S
+	KCALL
+	PUSH BYTE	10
+	OUT
+	PUSH STRING	@HELLO
+	PUSH STRING	@OUT_S
+	KCALL
+	EXIT
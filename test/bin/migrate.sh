@@ -13,3 +13,87 @@ for F in "$TESTROOT/$SRCGROUP"/*; do
 	cp "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" "$TESTROOT/$DESTGROUP/$FILENAME/data"
     fi
 done
+
+echo Migrating modules to dump tests...
+
+for F in "$TESTROOT/vpdump"/*; do
+    FILENAME=${F##*/}
+    if [ -e "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" ]
+    then
+	echo Copying "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" to "$TESTROOT/vpdump/$FILENAME/data"
+	cp "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" "$TESTROOT/vpdump/$FILENAME/data"
+    fi
+done
+echo
+
+echo Migrating modules to disassembler tests...
+
+for F in "$TESTROOT/disassembler"/*; do
+    FILENAME=${F##*/}
+    if [ -e "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" ]
+    then
+	echo Copying "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" to "$TESTROOT/disassembler/$FILENAME/data"
+	cp "$TESTROOT/$SRCGROUP/$FILENAME/ref/program.module" "$TESTROOT/disassembler/$FILENAME/data"
+    fi
+done
+echo
+
+echo Assembling librarian test modules...
+
+for F in "$TESTROOT/librarian"/*/data/*.asm; do
+    echo Assembling "$F"
+    go run assembler/assembler.go "$F" "${F%.asm}.module" >/dev/null
+done
+echo
+
+echo Migrating library archives...
+
+for F in "$TESTROOT/librarian"/*; do
+    FILENAME=${F##*/}
+    if [ -e "$TESTROOT/librarian/$FILENAME/ref/$FILENAME.lib" ] && [ -e "$TESTROOT/linker/$FILENAME/data/$FILENAME.lib" ]
+    then
+	echo Copying "$TESTROOT/librarian/$FILENAME/ref/$FILENAME.lib" to "$TESTROOT/linker/$FILENAME/data"
+	cp "$TESTROOT/librarian/$FILENAME/ref/$FILENAME.lib" "$TESTROOT/linker/$FILENAME/data"
+    fi
+done
+echo
+
+echo Assembling linker test modules...
+
+for F in "$TESTROOT/linker"/*/data/*.asm; do
+    echo Assembling "$F"
+    go run assembler/assembler.go "$F" "${F%.asm}.module" >/dev/null
+done
+echo
+
+echo Assembling runner library modules...
+
+for F in "$TESTROOT/$DESTGROUP"/*/data/*.asm; do
+    echo Assembling "$F"
+    go run assembler/assembler.go "$F" "${F%.asm}.module" >/dev/null
+done
+echo
+
+echo Migrating linked modules...
+
+for F in "$TESTROOT/linker"/*; do
+    FILENAME=${F##*/}
+    if [ -e "$TESTROOT/linker/$FILENAME/ref/program.module" ] && [ -e "$TESTROOT/$DESTGROUP/$FILENAME/data/program.module" ]
+    then
+	echo Copying "$TESTROOT/linker/$FILENAME/ref/program.module" to "$TESTROOT/$DESTGROUP/$FILENAME/data"
+	cp "$TESTROOT/linker/$FILENAME/ref/program.module" "$TESTROOT/$DESTGROUP/$FILENAME/data"
+    fi
+done
+echo
+
+echo Migrating linked modules to disassembler tests...
+
+for F in "$TESTROOT/disassembler"/*; do
+    FILENAME=${F##*/}
+    if [ -e "$TESTROOT/linker/$FILENAME/ref/program.module" ]
+    then
+	echo Copying "$TESTROOT/linker/$FILENAME/ref/program.module" to "$TESTROOT/disassembler/$FILENAME/data"
+	cp "$TESTROOT/linker/$FILENAME/ref/program.module" "$TESTROOT/disassembler/$FILENAME/data"
+    fi
+done
+echo
@@ -0,0 +1,51 @@
+echo
+TESTROOT=$1
+TESTBED=$2
+TESTGROUP=$3
+TESTNAME=$4
+OPTIONS=$5
+echo Start test $TESTNAME
+
+# create testbed
+echo Creating testbed...
+mkdir "$TESTBED/$TESTNAME"
+cp "$TESTROOT/$TESTGROUP/$TESTNAME/data"/* "$TESTBED/$TESTNAME"
+echo testbed ready
+
+# execute program
+ECODE=0
+
+echo Running program...
+go run disassembler/disassembler.go "$TESTBED/$TESTNAME/program.module" "$TESTBED/$TESTNAME/program.asm" >"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+echo run finished
+
+# reassemble, which must give the same module
+if [ -e "$TESTBED/$TESTNAME/program.asm" ]
+then
+    echo Reassembling...
+    ASMOPTIONS=$(sed -n 's/^# options: //p' "$TESTBED/$TESTNAME/program.asm")
+    go run assembler/assembler.go $ASMOPTIONS "$TESTBED/$TESTNAME/program.asm" "$TESTBED/$TESTNAME/reassembled.module" >/dev/null 2>&1
+    cmp "$TESTBED/$TESTNAME/program.module" "$TESTBED/$TESTNAME/reassembled.module" >>"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+    ((ECODE+=$?))
+    echo reassemble done
+fi
+
+# compare results
+for F in stdout.txt program.asm; do
+    if [ -e "$TESTBED/$TESTNAME/$F" ]
+    then
+	echo Comparing $F...
+	diff "$TESTBED/$TESTNAME/$F" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/$F"
+
+	if [ $? -ne 0 ]
+	then
+	    ((ECODE+=1))
+	    cp "$TESTBED/$TESTNAME/$F" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/$F"
+	fi
+
+	echo compare done
+    fi
+done
+
+echo End test $TESTNAME
+exit $ECODE
@@ -0,0 +1,73 @@
+echo
+TESTROOT=$1
+TESTBED=$2
+TESTGROUP=$3
+TESTNAME=$4
+OPTIONS=$5
+echo Start test $TESTNAME
+
+# create testbed
+echo Creating testbed...
+mkdir "$TESTBED/$TESTNAME"
+cp "$TESTROOT/$TESTGROUP/$TESTNAME/data"/* "$TESTBED/$TESTNAME"
+echo testbed ready
+
+# execute program
+ECODE=0
+
+MODULES=""
+for M in $(cat "$TESTBED/$TESTNAME/members.txt"); do
+    MODULES="$MODULES $TESTBED/$TESTNAME/$M"
+done
+
+echo Running program...
+go run librarian/librarian.go create "$TESTBED/$TESTNAME/$TESTNAME.lib" $MODULES >"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+if [ $? -eq 0 ]
+then
+    xxd -g 1 "$TESTBED/$TESTNAME/$TESTNAME.lib" >"$TESTBED/$TESTNAME/archive.dump"
+    go run librarian/librarian.go list "$TESTBED/$TESTNAME/$TESTNAME.lib" >>"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+fi
+
+# each line of extract.txt is one extract command
+if [ -e "$TESTBED/$TESTNAME/extract.txt" ]
+then
+    mkdir "$TESTBED/$TESTNAME/extracted"
+
+    while read -r NAMES; do
+	go run librarian/librarian.go --directory "$TESTBED/$TESTNAME/extracted" extract "$TESTBED/$TESTNAME/$TESTNAME.lib" $NAMES >>"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+    done <"$TESTBED/$TESTNAME/extract.txt"
+
+    for M in $(ls "$TESTBED/$TESTNAME/extracted"); do
+	cmp "$TESTBED/$TESTNAME/extracted/$M" "$TESTBED/$TESTNAME/$M" >>"$TESTBED/$TESTNAME/stdout.txt" 2>&1 && echo "$M matches" >>"$TESTBED/$TESTNAME/stdout.txt"
+    done
+fi
+echo run finished
+
+# compare results
+echo Comparing stdout...
+diff "$TESTBED/$TESTNAME/stdout.txt" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/stdout.txt"
+((ECODE+=$?))
+
+if [ $ECODE -ne 0 ]
+then
+    cp "$TESTBED/$TESTNAME/stdout.txt" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/stdout.txt"
+fi
+
+echo compare done
+
+if [ -e "$TESTBED/$TESTNAME/archive.dump" ]
+then
+    echo Comparing archive...
+    diff "$TESTBED/$TESTNAME/archive.dump" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/archive.dump"
+
+    if [ $? -ne 0 ]
+    then
+	cp "$TESTBED/$TESTNAME/archive.dump" "$TESTROOT/$TESTGROUP/$TESTNAME/ref"
+	cp "$TESTBED/$TESTNAME/$TESTNAME.lib" "$TESTROOT/$TESTGROUP/$TESTNAME/ref"
+    fi
+    
+    echo compare done
+fi
+
+echo End test $TESTNAME
+exit $ECODE
@@ -0,0 +1,58 @@
+echo
+TESTROOT=$1
+TESTBED=$2
+TESTGROUP=$3
+TESTNAME=$4
+OPTIONS=$5
+echo Start test $TESTNAME
+
+# create testbed
+echo Creating testbed...
+mkdir "$TESTBED/$TESTNAME"
+cp "$TESTROOT/$TESTGROUP/$TESTNAME/data"/* "$TESTBED/$TESTNAME"
+echo testbed ready
+
+# execute program
+ECODE=0
+
+MODULES=""
+for M in $(cat "$TESTBED/$TESTNAME/link.txt"); do
+    MODULES="$MODULES $TESTBED/$TESTNAME/$M"
+done
+
+echo Running program...
+go run linker/linker.go --output "$TESTBED/$TESTNAME/program.module" $MODULES >"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+if [ $? -eq 0 ]
+then
+    xxd -g 1 "$TESTBED/$TESTNAME/program.module" >"$TESTBED/$TESTNAME/module.dump"
+fi
+echo run finished
+
+# compare results
+echo Comparing stdout...
+diff "$TESTBED/$TESTNAME/stdout.txt" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/stdout.txt"
+((ECODE+=$?))
+
+if [ $ECODE -ne 0 ]
+then
+    cp "$TESTBED/$TESTNAME/stdout.txt" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/stdout.txt"
+fi
+
+echo compare done
+
+if [ -e "$TESTBED/$TESTNAME/module.dump" ]
+then
+    echo Comparing module...
+    diff "$TESTBED/$TESTNAME/module.dump" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/module.dump"
+
+    if [ $? -ne 0 ]
+    then
+	cp "$TESTBED/$TESTNAME/module.dump" "$TESTROOT/$TESTGROUP/$TESTNAME/ref"
+	cp "$TESTBED/$TESTNAME/program.module" "$TESTROOT/$TESTGROUP/$TESTNAME/ref"
+    fi
+    
+    echo compare done
+fi
+
+echo End test $TESTNAME
+exit $ECODE
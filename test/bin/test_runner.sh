@@ -15,8 +15,33 @@ echo testbed ready
 # execute program
 ECODE=0
 
+# console input, if the test has any
+INPUT=()
+if [ -e "$TESTBED/$TESTNAME/input.txt" ]
+then
+    INPUT=(--input "$TESTBED/$TESTNAME/input.txt")
+fi
+
+# program arguments, if the test has any
+ARGS=()
+if [ -e "$TESTBED/$TESTNAME/args.txt" ]
+then
+    read -r -a ARGS < "$TESTBED/$TESTNAME/args.txt"
+fi
+
+# runner options, if the test has any
+OPTS=()
+if [ -e "$TESTBED/$TESTNAME/options.txt" ]
+then
+    read -r -a OPTS < "$TESTBED/$TESTNAME/options.txt"
+    # TESTDIR in an option is the testbed directory
+    OPTS=("${OPTS[@]//TESTDIR/$TESTBED/$TESTNAME}")
+fi
+
 echo Running program...
-go run runner/runner.go --trace "$TESTBED/$TESTNAME/program.module" >"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+# random numbers and the clock are fixed, so the output is the same on every run
+# the trace goes to its own file, apart from the program's output
+go run runner/runner.go --trace --trace-file "$TESTBED/$TESTNAME/trace.txt" --seed 1 --clock 2000-01-02T12:34:56 --file-directory "$TESTBED/$TESTNAME" "${INPUT[@]}" "${OPTS[@]}" "$TESTBED/$TESTNAME/program.module" "${ARGS[@]}" >"$TESTBED/$TESTNAME/stdout.txt" 2>&1
 echo run finished
 
 # compare results
@@ -29,6 +54,23 @@ then
     cp "$TESTBED/$TESTNAME/stdout.txt" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/stdout.txt"
 fi
 
+# compare any other files the test writes
+for F in "$TESTROOT/$TESTGROUP/$TESTNAME/ref"/*; do
+    FILENAME=${F##*/}
+    if [ "$FILENAME" != stdout.txt ]
+    then
+	echo Comparing $FILENAME...
+	diff "$TESTBED/$TESTNAME/$FILENAME" "$F"
+	RESULT=$?
+	((ECODE+=$RESULT))
+
+	if [ $RESULT -ne 0 ]
+	then
+	    cp "$TESTBED/$TESTNAME/$FILENAME" "$F"
+	fi
+    fi
+done
+
 echo compare done
 
 echo End test $TESTNAME
@@ -0,0 +1,38 @@
+echo
+TESTROOT=$1
+TESTBED=$2
+TESTGROUP=$3
+TESTNAME=$4
+OPTIONS=$5
+echo Start test $TESTNAME
+
+# create testbed
+echo Creating testbed...
+mkdir "$TESTBED/$TESTNAME"
+cp "$TESTROOT/$TESTGROUP/$TESTNAME/data"/* "$TESTBED/$TESTNAME"
+echo testbed ready
+
+# execute program
+ECODE=0
+
+echo Running program...
+go run vpdump/vpdump.go "$TESTBED/$TESTNAME/program.module" >"$TESTBED/$TESTNAME/stdout.txt" 2>&1
+go run vpdump/vpdump.go --json "$TESTBED/$TESTNAME/program.module" >"$TESTBED/$TESTNAME/json.txt" 2>&1
+echo run finished
+
+# compare results
+for F in stdout.txt json.txt; do
+    echo Comparing $F...
+    diff "$TESTBED/$TESTNAME/$F" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/$F"
+
+    if [ $? -ne 0 ]
+    then
+	((ECODE+=1))
+	cp "$TESTBED/$TESTNAME/$F" "$TESTROOT/$TESTGROUP/$TESTNAME/ref/$F"
+    fi
+
+    echo compare done
+done
+
+echo End test $TESTNAME
+exit $ECODE
@@ -0,0 +1,8 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+MAIN:	PUSH BYTE	72
+	PUSH BYTE	10
+	ADD BYTE
+	OUT
+	EXIT
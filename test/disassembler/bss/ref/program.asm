@@ -0,0 +1,18 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 2
+
+data_0000:	BYTE	10
+data_0001:	RESERVE	1000
+data_03E9:	RESERVE	1
+data_03EA:	CONST STRING	"Buffer"
+
+MAIN:	PUSH BYTE	@data_03E9
+	FLAGS BYTE
+	NOT ZERO JUMP	FAIL
+	PUSH BYTE	66
+	POP BYTE	@data_03E9
+	PUSH BYTE	@data_03E9
+	OUT
+	PUSH BYTE	@data_0000
+	OUT
+FAIL:	EXIT
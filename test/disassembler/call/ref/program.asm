@@ -0,0 +1,21 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	STRING	"Hello, world!"
+data_0E:	BYTE	0
+data_0F:	BYTE	10
+
+MAIN:	PUSH BYTE	data_00
+	CALL	code_07
+	CALL	code_13
+	EXIT
+code_07:	POP BYTE	@data_0E
+code_09:	PUSH BYTE	@@data_0E
+	FLAGS BYTE
+	ZERO RET
+	OUT
+	INC BYTE	@data_0E
+	JUMP	code_09
+code_13:	PUSH BYTE	@data_0F
+	OUT
+	RET
@@ -0,0 +1,25 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	STRING	"Hello, world!"
+data_0E:	BYTE	0
+data_0F:	CONST STRING	"Linked"
+data_16:	CONST BYTE	10
+
+MAIN:	PUSH BYTE	data_00
+	CALL	PRINT_S
+	CALL	PRINT_NL
+	PUSH BYTE	data_0F
+	CALL	PRINT_S
+	CALL	PRINT_NL
+	EXIT
+PRINT_S:	POP BYTE	@data_0E
+code_0F:	PUSH BYTE	@@data_0E
+	FLAGS BYTE
+	ZERO RET
+	OUT
+	INC BYTE	@data_0E
+	JUMP	code_0F
+PRINT_NL:	PUSH BYTE	@data_16
+	OUT
+	RET
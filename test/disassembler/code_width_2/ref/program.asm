@@ -0,0 +1,256 @@
+# disassembled from program.module
+# options: --code-width 2 --data-width 1
+
+MAIN:	CALL	code_000A
+	CALL	code_0092
+	CALL	code_010E
+	EXIT
+code_000A:	PUSH BYTE	84
+	OUT
+	PUSH BYTE	104
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	113
+	OUT
+	PUSH BYTE	117
+	OUT
+	PUSH BYTE	105
+	OUT
+	PUSH BYTE	99
+	OUT
+	PUSH BYTE	107
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	98
+	OUT
+	PUSH BYTE	114
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	119
+	OUT
+	PUSH BYTE	110
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	102
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	120
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	106
+	OUT
+	PUSH BYTE	117
+	OUT
+	PUSH BYTE	109
+	OUT
+	PUSH BYTE	112
+	OUT
+	PUSH BYTE	115
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	118
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	114
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	116
+	OUT
+	PUSH BYTE	104
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	108
+	OUT
+	PUSH BYTE	97
+	OUT
+	PUSH BYTE	122
+	OUT
+	PUSH BYTE	121
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	100
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	103
+	OUT
+	PUSH BYTE	46
+	OUT
+	PUSH BYTE	10
+	OUT
+	RET
+code_0092:	PUSH BYTE	80
+	OUT
+	PUSH BYTE	97
+	OUT
+	PUSH BYTE	99
+	OUT
+	PUSH BYTE	107
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	109
+	OUT
+	PUSH BYTE	121
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	98
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	120
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	119
+	OUT
+	PUSH BYTE	105
+	OUT
+	PUSH BYTE	116
+	OUT
+	PUSH BYTE	104
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	102
+	OUT
+	PUSH BYTE	105
+	OUT
+	PUSH BYTE	118
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	100
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	122
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	110
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	108
+	OUT
+	PUSH BYTE	105
+	OUT
+	PUSH BYTE	113
+	OUT
+	PUSH BYTE	117
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	114
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	106
+	OUT
+	PUSH BYTE	117
+	OUT
+	PUSH BYTE	103
+	OUT
+	PUSH BYTE	115
+	OUT
+	PUSH BYTE	46
+	OUT
+	PUSH BYTE	10
+	OUT
+	RET
+code_010E:	PUSH BYTE	72
+	OUT
+	PUSH BYTE	111
+	OUT
+	PUSH BYTE	119
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	118
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	120
+	OUT
+	PUSH BYTE	105
+	OUT
+	PUSH BYTE	110
+	OUT
+	PUSH BYTE	103
+	OUT
+	PUSH BYTE	108
+	OUT
+	PUSH BYTE	121
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	113
+	OUT
+	PUSH BYTE	117
+	OUT
+	PUSH BYTE	105
+	OUT
+	PUSH BYTE	99
+	OUT
+	PUSH BYTE	107
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	100
+	OUT
+	PUSH BYTE	97
+	OUT
+	PUSH BYTE	102
+	OUT
+	PUSH BYTE	116
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	122
+	OUT
+	PUSH BYTE	101
+	OUT
+	PUSH BYTE	98
+	OUT
+	PUSH BYTE	114
+	OUT
+	PUSH BYTE	97
+	OUT
+	PUSH BYTE	115
+	OUT
+	PUSH BYTE	32
+	OUT
+	PUSH BYTE	106
+	OUT
+	PUSH BYTE	117
+	OUT
+	PUSH BYTE	109
+	OUT
+	PUSH BYTE	112
+	OUT
+	PUSH BYTE	33
+	OUT
+	PUSH BYTE	10
+	OUT
+	RET
@@ -0,0 +1,15 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	BYTE	0
+data_01:	CONST STRING	"Hello, world!"
+
+MAIN:	PUSH STRING	@data_01
+	POP BYTE	@data_00
+LOOP:	FLAGS BYTE
+	ZERO JUMP	NEWLINE
+	OUT
+	JUMP	LOOP
+NEWLINE:	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,16 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 2
+
+data_0000:	STRING	"Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore."
+data_0136:	STRING	"Hello, world!"
+data_0144:	BYTE	0
+
+MAIN:	PUSH STRING	@data_0136
+	POP BYTE	@data_0144
+LOOP:	FLAGS BYTE
+	ZERO JUMP	NEWLINE
+	OUT
+	JUMP	LOOP
+NEWLINE:	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,16 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+	EXTERNAL	PRINT_NL
+	EXTERNAL	PRINT_S
+
+data_00:	STRING	"Hello, world!"
+data_0E:	CONST STRING	"Linked"
+
+MAIN:	PUSH BYTE	data_00
+	CALL	PRINT_S
+	CALL	PRINT_NL
+	PUSH BYTE	data_0E
+	CALL	PRINT_S
+	CALL	PRINT_NL
+	EXIT
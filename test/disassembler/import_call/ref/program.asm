@@ -0,0 +1,11 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	CONST BYTE	105
+
+MAIN:	PUSH BYTE	72
+	CALL	stdlib.PRINT_B
+	PUSH BYTE	@data_00
+	CALL	stdlib.PRINT_B
+	CALL	stdlib.PRINT_NL
+	EXIT
@@ -0,0 +1,30 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	STRING	"Value is zero"
+data_0E:	STRING	"Value is not zero"
+data_20:	BYTE	0
+data_21:	BYTE	10
+
+MAIN:	PUSH BYTE	0
+	FLAGS BYTE
+	POP BYTE
+	NOT ZERO JUMP	code_10
+	PUSH BYTE	data_00
+	CALL	code_17
+	CALL	code_23
+	JUMP	code_16
+code_10:	PUSH BYTE	data_0E
+	CALL	code_17
+	CALL	code_23
+code_16:	EXIT
+code_17:	POP BYTE	@data_20
+code_19:	PUSH BYTE	@@data_20
+	FLAGS BYTE
+	ZERO RET
+	OUT
+	INC BYTE	@data_20
+	JUMP	code_19
+code_23:	PUSH BYTE	@data_21
+	OUT
+	RET
@@ -0,0 +1,30 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	STRING	"Value is zero"
+data_0E:	STRING	"Value is not zero"
+data_20:	BYTE	0
+data_21:	BYTE	10
+
+MAIN:	PUSH BYTE	0
+	FLAGS BYTE
+	POP BYTE
+	ZERO JUMP	code_0F
+	PUSH BYTE	data_0E
+	CALL	code_16
+	CALL	code_22
+	JUMP	code_15
+code_0F:	PUSH BYTE	data_00
+	CALL	code_16
+	CALL	code_22
+code_15:	EXIT
+code_16:	POP BYTE	@data_20
+code_18:	PUSH BYTE	@@data_20
+	FLAGS BYTE
+	ZERO RET
+	OUT
+	INC BYTE	@data_20
+	JUMP	code_18
+code_22:	PUSH BYTE	@data_21
+	OUT
+	RET
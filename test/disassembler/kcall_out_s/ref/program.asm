@@ -0,0 +1,14 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	STRING	"out_s"
+data_06:	STRING	"out_b"
+data_0C:	STRING	"Hello, world!"
+
+MAIN:	PUSH STRING	@data_0C
+	PUSH STRING	@data_00
+	KCALL
+	PUSH BYTE	10
+	PUSH STRING	@data_06
+	KCALL
+	EXIT
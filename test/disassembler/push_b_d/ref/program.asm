@@ -0,0 +1,8 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	BYTE	72
+
+MAIN:	PUSH BYTE	@data_00
+	OUT
+	EXIT
@@ -0,0 +1,7 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+MAIN:	PUSH I16	14920
+	OUT
+	OUT
+	EXIT
@@ -0,0 +1,15 @@
+# disassembled from program.module
+# options: --code-width 1 --data-width 1
+
+data_00:	STRING	"Hello, world!"
+data_0E:	BYTE	0
+
+MAIN:	PUSH STRING	@data_00
+	POP BYTE	@data_0E
+LOOP:	FLAGS BYTE
+	ZERO JUMP	NEWLINE
+	OUT
+	JUMP	LOOP
+NEWLINE:	PUSH BYTE	10
+	OUT
+	EXIT
@@ -0,0 +1,5 @@
+# sound the bell
+bell:	CONST BYTE	7
+BEEP:	PUSH BYTE	@bell
+	OUT
+	RET
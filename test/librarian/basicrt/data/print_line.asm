@@ -0,0 +1,7 @@
+	EXTERNAL	PRINT_S
+	EXTERNAL	PRINT_NL
+
+# print a string and a newline
+PRINT_LINE:	CALL	PRINT_S
+	CALL	PRINT_NL
+	RET
@@ -0,0 +1,5 @@
+# print a newline
+newline:	CONST BYTE	10
+PRINT_NL:	PUSH BYTE	@newline
+	OUT
+	RET
@@ -0,0 +1,9 @@
+# print a string
+address:	BYTE	0
+PRINT_S:	POP BYTE	@address
+loop:	PUSH BYTE	@@address
+	FLAGS BYTE
+	ZERO RET
+	OUT
+	INC BYTE	@address
+	JUMP	loop
@@ -0,0 +1,8 @@
+	EXTERNAL	PRINT_LINE
+
+message:	STRING	"Hello, library!"
+
+# main program
+MAIN:	PUSH BYTE	message
+	CALL	PRINT_LINE
+	EXIT
@@ -0,0 +1,14 @@
+	EXTERNAL	PRINT_S
+	EXTERNAL	PRINT_NL
+
+message:	STRING	"Hello, world!"
+greeting:	CONST STRING	"Linked"
+
+# main program
+MAIN:	PUSH BYTE	message
+	CALL	PRINT_S
+	CALL	PRINT_NL
+	PUSH BYTE	greeting
+	CALL	PRINT_S
+	CALL	PRINT_NL
+	EXIT
@@ -0,0 +1,18 @@
+	EXTERNAL	PRINT_NL
+
+OUT_S:	STRING	"out_s"
+message:	STRING	"Hello, "
+count:	BYTE	3
+star:	BYTE	42
+
+# narrow addresses, widened by the linker
+MAIN:	PUSH STRING	@message
+	PUSH STRING	@OUT_S
+	KCALL
+loop:	PUSH BYTE	@star
+	OUT
+	DEC BYTE	@count
+	FLAGS BYTE	@count
+	NOT ZERO JUMP	loop
+	CALL	PRINT_NL
+	EXIT
@@ -0,0 +1,15 @@
+# print a string
+address:	BYTE	0
+PRINT_S:	POP BYTE	@address
+loop:	PUSH BYTE	@@address
+	FLAGS BYTE
+	ZERO RET
+	OUT
+	INC BYTE	@address
+	JUMP	loop
+
+# print a newline
+newline:	CONST BYTE	10
+PRINT_NL:	PUSH BYTE	@newline
+	OUT
+	RET
@@ -0,0 +1,7 @@
+	EXTERNAL	PRINT_NUMBER
+
+MAIN:	CALL	PRINT_S
+	CALL	PRINT_NUMBER
+	EXIT
+
+PRINT_S:	RET
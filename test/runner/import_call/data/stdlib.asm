@@ -0,0 +1,9 @@
+# print a byte from the value stack
+PRINT_B:	OUT
+	RET
+
+# print a newline
+newline:	CONST BYTE	10
+PRINT_NL:	PUSH BYTE	@newline
+	OUT
+	RET
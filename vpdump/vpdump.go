@@ -0,0 +1,266 @@
+/*
+Package main of module dump
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/jfitz/virtual-processor/module"
+	"github.com/jfitz/virtual-processor/vputils"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type jsonNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type jsonInstruction struct {
+	Address int    `json:"address"`
+	Label   string `json:"label,omitempty"`
+	Bytes   string `json:"bytes"`
+	Text    string `json:"text"`
+}
+
+type jsonModule struct {
+	Properties       []jsonNameValue   `json:"properties"`
+	Exports          []jsonNameValue   `json:"exports"`
+	Externals        []jsonNameValue   `json:"externals"`
+	Imports          []jsonNameValue   `json:"imports"`
+	Relocations      []jsonNameValue   `json:"relocations"`
+	CodeProperties   []jsonNameValue   `json:"code_properties"`
+	CodeAddressWidth int               `json:"code_address_width"`
+	Code             []jsonInstruction `json:"code"`
+	Undecoded        string            `json:"undecoded,omitempty"`
+	CodeError        string            `json:"code_error,omitempty"`
+	DataProperties   []jsonNameValue   `json:"data_properties"`
+	DataAddressWidth int               `json:"data_address_width"`
+	Data             string            `json:"data"`
+	BSSSize          int               `json:"bss_size"`
+	ConstProperties  []jsonNameValue   `json:"const_properties"`
+	Const            string            `json:"const"`
+}
+
+// code labels from the exports
+func makeLabels(mod module.Module) map[int]string {
+	labels := make(map[int]string)
+
+	for _, export := range mod.Exports {
+		address, err := strconv.Atoi(export.Value)
+		if err == nil {
+			labels[address] = export.Name
+		}
+	}
+
+	return labels
+}
+
+func hexString(bytes []byte) string {
+	ss := []string{}
+
+	for _, b := range bytes {
+		ss = append(ss, fmt.Sprintf("%02X", b))
+	}
+
+	return strings.Join(ss, " ")
+}
+
+func printableString(bytes []byte) string {
+	s := ""
+
+	for _, b := range bytes {
+		if b >= 0x20 && b < 0x7f {
+			s += string(b)
+		} else {
+			s += "."
+		}
+	}
+
+	return s
+}
+
+func printTable(name string, table []vputils.NameValue) {
+	tabs := "\t\t\t"
+	fmt.Println(tabs + name)
+
+	for _, nameValue := range table {
+		fmt.Printf("%s\t%s\n", nameValue.Name, nameValue.Value)
+	}
+
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+}
+
+// hex dump of bytes, starting at a data address
+func printDump(name string, bytes []byte, start int, dataAddressWidth int) {
+	tabs := "\t\t\t"
+	fmt.Println(tabs + name)
+
+	dataFormat := vputils.AddressFormat(dataAddressWidth)
+
+	for i := 0; i < len(bytes); i += 16 {
+		end := i + 16
+		if end > len(bytes) {
+			end = len(bytes)
+		}
+
+		line := bytes[i:end]
+		fmt.Printf(dataFormat+"\t%-47s\t%s\n", start+i, hexString(line), printableString(line))
+	}
+
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+}
+
+// the code that follows the last instruction that could be decoded
+func undecoded(mod module.Module, instructions []module.DecodedInstruction) (int, []byte) {
+	start := 0
+
+	if len(instructions) > 0 {
+		last := instructions[len(instructions)-1]
+		start = last.Address + last.Size()
+	}
+
+	return start, mod.CodePage.Contents[start:]
+}
+
+func printCode(mod module.Module, instructions []module.DecodedInstruction) {
+	tabs := "\t\t\t"
+	fmt.Println(tabs + "CODE")
+
+	labels := makeLabels(mod)
+	codeFormat := vputils.AddressFormat(mod.CodeAddressWidth)
+
+	for _, instruction := range instructions {
+		if label, ok := labels[instruction.Address]; ok {
+			fmt.Println(label + ":")
+		}
+
+		fmt.Printf(codeFormat+"\t%s\t\t%s\n", instruction.Address, instruction.ToByteString(), instruction.ToString(mod))
+	}
+
+	// bytes that could not be decoded are shown as they are
+	start, bytes := undecoded(mod, instructions)
+	for i := 0; i < len(bytes); i += 8 {
+		end := i + 8
+		if end > len(bytes) {
+			end = len(bytes)
+		}
+
+		fmt.Printf(codeFormat+"\t%s\t\t%s\n", start+i, hexString(bytes[i:end]), "?")
+	}
+
+	fmt.Println(tabs + "ENDSEGMENT")
+	fmt.Println()
+}
+
+func dumpText(mod module.Module, instructions []module.DecodedInstruction) {
+	printTable("PROPERTIES", mod.Properties)
+	module.PrintExports(mod.Exports, mod.CodeAddressWidth)
+	printTable("EXTERNALS", mod.Externals)
+	printTable("IMPORTS", mod.Imports)
+	printTable("RELOCATIONS", mod.Relocations)
+	printTable("CODE_PROPERTIES", mod.CodePage.Properties)
+	printCode(mod, instructions)
+	printTable("DATA_PROPERTIES", mod.DataPage.Properties)
+
+	dataSize := len(mod.DataPage.Contents)
+	printDump("DATA", mod.DataPage.Contents, 0, mod.DataAddressWidth)
+
+	bss := []vputils.NameValue{{"SIZE", strconv.Itoa(mod.BSSSize)}}
+	printTable("BSS", bss)
+
+	printTable("CONST_PROPERTIES", mod.ConstPage.Properties)
+	printDump("CONST", mod.ConstPage.Contents, dataSize+mod.BSSSize, mod.DataAddressWidth)
+}
+
+func makeJSONTable(table []vputils.NameValue) []jsonNameValue {
+	result := []jsonNameValue{}
+
+	for _, nameValue := range table {
+		result = append(result, jsonNameValue{nameValue.Name, nameValue.Value})
+	}
+
+	return result
+}
+
+func dumpJSON(mod module.Module, instructions []module.DecodedInstruction, codeErr error) {
+	labels := makeLabels(mod)
+
+	code := []jsonInstruction{}
+	for _, instruction := range instructions {
+		label := labels[instruction.Address]
+		item := jsonInstruction{instruction.Address, label, instruction.ToByteString(), instruction.ToString(mod)}
+		code = append(code, item)
+	}
+
+	_, bytes := undecoded(mod, instructions)
+
+	codeError := ""
+	if codeErr != nil {
+		codeError = codeErr.Error()
+	}
+
+	dump := jsonModule{
+		Properties:       makeJSONTable(mod.Properties),
+		Exports:          makeJSONTable(mod.Exports),
+		Externals:        makeJSONTable(mod.Externals),
+		Imports:          makeJSONTable(mod.Imports),
+		Relocations:      makeJSONTable(mod.Relocations),
+		CodeProperties:   makeJSONTable(mod.CodePage.Properties),
+		CodeAddressWidth: mod.CodeAddressWidth,
+		Code:             code,
+		Undecoded:        hexString(bytes),
+		CodeError:        codeError,
+		DataProperties:   makeJSONTable(mod.DataPage.Properties),
+		DataAddressWidth: mod.DataAddressWidth,
+		Data:             hexString(mod.DataPage.Contents),
+		BSSSize:          mod.BSSSize,
+		ConstProperties:  makeJSONTable(mod.ConstPage.Properties),
+		Const:            hexString(mod.ConstPage.Contents),
+	}
+
+	text, err := json.MarshalIndent(dump, "", "  ")
+	vputils.CheckAndExit(err)
+
+	fmt.Println(string(text))
+}
+
+func main() {
+	jsonPtr := flag.Bool("json", false, "Write the dump as JSON.")
+
+	flag.Parse()
+
+	asJSON := *jsonPtr
+
+	args := flag.Args()
+
+	if len(args) == 0 {
+		fmt.Println("No module file specified")
+		os.Exit(1)
+	}
+
+	moduleFile := args[0]
+
+	mod, err := module.Read(moduleFile)
+	vputils.CheckPrintAndExit(err, "in "+moduleFile)
+
+	// a bad instruction stops decoding, but the rest of the module is still dumped
+	instructions, codeErr := module.Disassemble(mod)
+
+	if asJSON {
+		dumpJSON(mod, instructions, codeErr)
+
+		if codeErr != nil {
+			os.Exit(1)
+		}
+	} else {
+		dumpText(mod, instructions)
+
+		vputils.CheckAndExit(codeErr)
+	}
+}
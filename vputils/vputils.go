@@ -4,9 +4,12 @@ Package vputils for virtual-processor
 package vputils
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -41,10 +44,17 @@ func CheckPrintAndExit(e error, message string) {
 	}
 }
 
-func checkWidth(width int) {
-	if width != 1 && width != 2 {
-		CheckAndExit(errors.New("Invalid width"))
+// AddressWidth - smallest address width that can hold a size
+func AddressWidth(size int) int {
+	if size < 0x100 {
+		return 1
 	}
+
+	if size < 0x10000 {
+		return 2
+	}
+
+	return 4
 }
 
 // IsSpace - is it a space
@@ -167,96 +177,125 @@ func Tokenize(s string) []string {
 	return parts
 }
 
-func read1ByteInt(f *os.File) int {
-	bytes := make([]byte, 1)
-	_, err := f.Read(bytes)
-	CheckAndPanic(err)
+// MaxStringLength - longest string accepted in a module file
+const MaxStringLength = 1024
 
-	value := int(bytes[0])
+// MaxBlockSize - largest binary block or reserved size accepted in a module file
+const MaxBlockSize = 1 << 24
 
-	return value
+func readBytes(r io.Reader, count int) ([]byte, error) {
+	// copy rather than allocate the count, so a bad count cannot exhaust memory
+	buffer := new(bytes.Buffer)
+	n, err := io.CopyN(buffer, r, int64(count))
+	if int(n) < count {
+		return nil, errors.New("Unexpected end of file")
+	}
+
+	return buffer.Bytes(), err
 }
 
-func read2ByteInt(f *os.File) int {
-	bytes := make([]byte, 2)
-	_, err := f.Read(bytes)
-	CheckAndPanic(err)
+func readWidthInt(r io.Reader, width int) (int, error) {
+	bytes, err := readBytes(r, width)
+	if err != nil {
+		return 0, err
+	}
 
-	value := int(bytes[1])<<8 + int(bytes[0])
+	value := 0
+	for i := width; i > 0; i-- {
+		value = value<<8 + int(bytes[i-1])
+	}
 
-	return value
+	return value, nil
 }
 
-// if value is greater than 255 then error
-func write1ByteInt(f *os.File, value int) {
-	low := byte(value & 0x00ff)
-	bytes := []byte{low}
+func writeWidthInt(w io.Writer, value int, width int) error {
+	if value >= 1<<uint(8*width) {
+		return errors.New("Value " + strconv.Itoa(value) + " does not fit in width " + strconv.Itoa(width))
+	}
 
-	_, err := f.Write(bytes)
-	CheckAndPanic(err)
-}
+	bytes := make([]byte, width)
+	for i := 0; i < width; i++ {
+		bytes[i] = byte(value >> uint(8*i) & 0xff)
+	}
 
-// if value is greater than 65535 then error
-func write2ByteInt(f *os.File, value int) {
-	high := byte(value & 0xff00 >> 8)
-	low := byte(value & 0x00ff)
-	bytes := []byte{low, high}
+	_, err := w.Write(bytes)
 
-	_, err := f.Write(bytes)
-	CheckAndPanic(err)
+	return err
 }
 
 // ReadString - read a string from a module file
-func ReadString(f *os.File) string {
+func ReadString(r io.Reader) (string, error) {
 	bytes := []byte{}
 	oneByte := make([]byte, 1)
-	oneByte[0] = 1
-	for oneByte[0] != 0 {
-		_, err := f.Read(oneByte)
-		CheckAndPanic(err)
-		if oneByte[0] != 0 {
-			bytes = append(bytes, oneByte...)
+
+	for {
+		_, err := io.ReadFull(r, oneByte)
+		if err != nil {
+			return "", errors.New("Unexpected end of file")
+		}
+
+		if oneByte[0] == 0 {
+			break
+		}
+
+		if len(bytes) == MaxStringLength {
+			return "", errors.New("String too long")
 		}
+
+		bytes = append(bytes, oneByte...)
 	}
 
 	name := string(bytes)
 
-	return name
+	return name, nil
 }
 
 // WriteString - write a string to a module file
-func WriteString(f *os.File, text string) {
-	_, err := f.Write([]byte(text))
-	CheckAndPanic(err)
+func WriteString(w io.Writer, text string) error {
+	_, err := w.Write([]byte(text))
+	if err != nil {
+		return err
+	}
 
 	zeroByte := []byte{0}
 
-	_, err = f.Write(zeroByte)
-	CheckAndPanic(err)
+	_, err = w.Write(zeroByte)
+
+	return err
+}
+
+func checkWidth(width int) error {
+	if width != 1 && width != 2 && width != 4 {
+		return errors.New("Invalid width")
+	}
+
+	return nil
 }
 
 // ReadBinaryBlock - read a binary block from a module file
-func ReadBinaryBlock(f *os.File, width int) ([]byte, error) {
-	checkWidth(width)
+func ReadBinaryBlock(r io.Reader, width int) ([]byte, error) {
+	err := checkWidth(width)
+	if err != nil {
+		return nil, err
+	}
+
+	countBytes, err := readWidthInt(r, width)
+	if err != nil {
+		return nil, err
+	}
 
-	countBytes := 0
-	switch width {
-	case 1:
-		countBytes = read1ByteInt(f)
-	case 2:
-		countBytes = read2ByteInt(f)
+	if countBytes > MaxBlockSize {
+		return nil, errors.New("Block too large")
 	}
 
-	code := make([]byte, countBytes)
-	_, err := f.Read(code)
-	CheckAndPanic(err)
+	code, err := readBytes(r, countBytes)
+	if err != nil {
+		return nil, err
+	}
 
-	checkCountBytes := 0
-	switch width {
-	case 1:
-		checkCountBytes = read1ByteInt(f)
-	case 2:
-		checkCountBytes = read2ByteInt(f)
+	checkCountBytes, err := readWidthInt(r, width)
+	if err != nil {
+		return nil, err
 	}
 
 	if checkCountBytes != countBytes {
@@ -267,30 +306,66 @@ func ReadBinaryBlock(f *os.File, width int) ([]byte, error) {
 }
 
 // WriteBinaryBlock - write a binary block to a module file
-func WriteBinaryBlock(name string, bytes []byte, f *os.File, width int) {
-	checkWidth(width)
+func WriteBinaryBlock(name string, bytes []byte, w io.Writer, width int) error {
+	err := checkWidth(width)
+	if err != nil {
+		return err
+	}
+
+	err = WriteString(w, name)
+	if err != nil {
+		return err
+	}
+
+	err = writeWidthInt(w, len(bytes), width)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(bytes)
+	if err != nil {
+		return err
+	}
+
+	return writeWidthInt(w, len(bytes), width)
+}
 
-	WriteString(f, name)
-	switch width {
-	case 1:
-		write1ByteInt(f, len(bytes))
-	case 2:
-		write2ByteInt(f, len(bytes))
+// ReadSizeBlock - read a size-only block from a module file
+func ReadSizeBlock(r io.Reader, width int) (int, error) {
+	err := checkWidth(width)
+	if err != nil {
+		return 0, err
 	}
 
-	_, err := f.Write(bytes)
-	CheckAndPanic(err)
+	size, err := readWidthInt(r, width)
+	if err != nil {
+		return 0, err
+	}
 
-	switch width {
-	case 1:
-		write1ByteInt(f, len(bytes))
-	case 2:
-		write2ByteInt(f, len(bytes))
+	if size > MaxBlockSize {
+		return 0, errors.New("Block too large")
 	}
+
+	return size, nil
+}
+
+// WriteSizeBlock - write a size-only block to a module file
+func WriteSizeBlock(name string, size int, w io.Writer, width int) error {
+	err := checkWidth(width)
+	if err != nil {
+		return err
+	}
+
+	err = WriteString(w, name)
+	if err != nil {
+		return err
+	}
+
+	return writeWidthInt(w, size, width)
 }
 
 // ReadTextTable - read a text table from a module file
-func ReadTextTable(f *os.File) ([]NameValue, error) {
+func ReadTextTable(r io.Reader) ([]NameValue, error) {
 	stxByte := []byte{0x02}
 	etxByte := []byte{0x03}
 	fsByte := []byte{0x1c}
@@ -301,7 +376,7 @@ func ReadTextTable(f *os.File) ([]NameValue, error) {
 	nameValues := []NameValue{}
 
 	// read STX
-	_, err := f.Read(oneByte)
+	_, err := io.ReadFull(r, oneByte)
 	if err != nil {
 		return nameValues, errors.New("Could not read byte")
 	}
@@ -314,12 +389,16 @@ func ReadTextTable(f *os.File) ([]NameValue, error) {
 	bytes := []byte{}
 	oneByte[0] = 0
 	for oneByte[0] != etxByte[0] {
-		_, err := f.Read(oneByte)
+		_, err := io.ReadFull(r, oneByte)
 		if err != nil {
 			return nameValues, errors.New("Could not read byte")
 		}
 
 		if oneByte[0] != etxByte[0] {
+			if len(bytes) == MaxBlockSize {
+				return nameValues, errors.New("Text table too large")
+			}
+
 			bytes = append(bytes, oneByte...)
 		}
 	}
@@ -341,8 +420,11 @@ func ReadTextTable(f *os.File) ([]NameValue, error) {
 }
 
 // WriteTextTable - write a text table to a module file
-func WriteTextTable(name string, table []NameValue, f *os.File) error {
-	WriteString(f, name)
+func WriteTextTable(name string, table []NameValue, w io.Writer) error {
+	err := WriteString(w, name)
+	if err != nil {
+		return err
+	}
 
 	stxByte := []byte{0x02}
 	etxByte := []byte{0x03}
@@ -350,40 +432,42 @@ func WriteTextTable(name string, table []NameValue, f *os.File) error {
 	rsByte := []byte{0x1e}
 
 	// write STX
-	_, err := f.Write(stxByte)
-	CheckAndPanic(err)
+	_, err = w.Write(stxByte)
+	if err != nil {
+		return errors.New("Failed to write STX")
+	}
 
 	for _, nameValue := range table {
 		name := []byte(nameValue.Name)
 		value := []byte(nameValue.Value)
 
 		// write name
-		_, err = f.Write(name)
+		_, err = w.Write(name)
 		if err != nil {
 			return errors.New("Failed to write name")
 		}
 
 		// write FS
-		_, err = f.Write(fsByte)
+		_, err = w.Write(fsByte)
 		if err != nil {
 			return errors.New("Failed to write FS")
 		}
 
 		// write value
-		_, err = f.Write(value)
+		_, err = w.Write(value)
 		if err != nil {
 			return errors.New("Failed to write bytes")
 		}
 
 		// write RS (0x1e)
-		_, err = f.Write(rsByte)
+		_, err = w.Write(rsByte)
 		if err != nil {
 			return errors.New("Failed to write RS")
 		}
 
 	}
 	// write ETX
-	_, err = f.Write(etxByte)
+	_, err = w.Write(etxByte)
 
 	return err
 }
@@ -412,16 +496,18 @@ type Address struct {
 func addressSpecification(size int) string {
 	spec := "%X"
 
-	if size == 1 {
-		spec = "%02X"
-	}
-	if size == 2 {
-		spec = "%04X"
+	if size > 0 {
+		spec = fmt.Sprintf("%%0%dX", size*2)
 	}
 
 	return spec
 }
 
+// AddressFormat - printf format for an address of the given width
+func AddressFormat(size int) string {
+	return addressSpecification(size)
+}
+
 // MakeAddress - create an address
 func MakeAddress(value int, size int, maximum int) (Address, error) {
 	if value < 0 {
@@ -460,16 +546,15 @@ func (address Address) Empty() bool {
 	return address.Size == 0
 }
 
-// ToString - convert to string
+// ToString - convert to string, most significant byte first
 func (address Address) ToString() string {
-	bytes := address.ToBytes()
-	ss := []string{}
-	for _, b := range bytes {
-		s := fmt.Sprintf("%02X", b)
-		ss = append(ss, s)
+	if address.Empty() {
+		return ""
 	}
-	result := strings.Join(ss, " ")
-	return result
+
+	spec := addressSpecification(address.Size)
+
+	return fmt.Sprintf(spec, address.Value)
 }
 
 // ToBytes - convert to array of bytes
@@ -657,6 +742,77 @@ func (stack ByteStack) PopString() (string, ByteStack) {
 	return s, stack
 }
 
+// PushI16 - push a 16-bit integer, low byte on top
+func (stack ByteStack) PushI16(value int16) ByteStack {
+	bytes := []byte{byte(uint16(value) & 0xff), byte(uint16(value) >> 8)}
+
+	return stack.PushBytes(bytes)
+}
+
+// PopI16 - pop a 16-bit integer
+func (stack ByteStack) PopI16() (int16, ByteStack, error) {
+	bytes, stack, err := stack.PopByte(2)
+	if err != nil {
+		return 0, stack, err
+	}
+
+	value := uint16(bytes[0])<<8 | uint16(bytes[1])
+
+	return int16(value), stack, nil
+}
+
+// PushI32 - push a 32-bit integer, low byte on top
+func (stack ByteStack) PushI32(value int32) ByteStack {
+	bytes := []byte{}
+	for i := 0; i < 4; i++ {
+		bytes = append(bytes, byte(uint32(value)>>(uint(i)*8)))
+	}
+
+	return stack.PushBytes(bytes)
+}
+
+// PopI32 - pop a 32-bit integer
+func (stack ByteStack) PopI32() (int32, ByteStack, error) {
+	bytes, stack, err := stack.PopByte(4)
+	if err != nil {
+		return 0, stack, err
+	}
+
+	value := uint32(0)
+	for _, b := range bytes {
+		value = value<<8 | uint32(b)
+	}
+
+	return int32(value), stack, nil
+}
+
+// PushF64 - push a 64-bit float, low byte on top
+func (stack ByteStack) PushF64(value float64) ByteStack {
+	bits := math.Float64bits(value)
+
+	bytes := []byte{}
+	for i := 0; i < 8; i++ {
+		bytes = append(bytes, byte(bits>>(uint(i)*8)))
+	}
+
+	return stack.PushBytes(bytes)
+}
+
+// PopF64 - pop a 64-bit float
+func (stack ByteStack) PopF64() (float64, ByteStack, error) {
+	bytes, stack, err := stack.PopByte(8)
+	if err != nil {
+		return 0, stack, err
+	}
+
+	bits := uint64(0)
+	for _, b := range bytes {
+		bits = bits<<8 | uint64(b)
+	}
+
+	return math.Float64frombits(bits), stack, nil
+}
+
 // ToByteString - convert to string of byte representation
 func (stack ByteStack) ToByteString() string {
 	s := ""